@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clusterSlots Redis Cluster的slot数量
+const clusterSlots = 16384
+
+// keySlot 计算键所在的集群slot，与Redis一样只对hash tag({...})内的部分计算CRC16
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 CRC16/XMODEM校验
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// groupKeysBySlot 集群模式下按slot分组，使每个多键命令只涉及一个slot；非集群模式下返回单个分组
+func groupKeysBySlot(client redis.UniversalClient, keys []string) [][]string {
+	if _, ok := client.(*redis.ClusterClient); !ok {
+		return [][]string{keys}
+	}
+
+	index := make(map[int]int)
+	var groups [][]string
+	for _, key := range keys {
+		slot := keySlot(key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// decodeMap 将原始值解码到dest指向的map中，dest必须为 *map[string]T
+func decodeMap(raw map[string]string, dest interface{}, decode func([]byte, interface{}) error) error {
+	ptr := reflect.ValueOf(dest)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Map || ptr.Elem().Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cache: MGet dest must be a non-nil *map[string]T, got %T", dest)
+	}
+
+	m := ptr.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMapWithSize(m.Type(), len(raw)))
+	}
+	elemType := m.Type().Elem()
+	for key, data := range raw {
+		value := reflect.New(elemType)
+		if err := decode([]byte(data), value.Interface()); err != nil {
+			return fmt.Errorf("failed to decode key %s: %w", key, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(m.Type().Key()), value.Elem())
+	}
+	return nil
+}
+
+// mgetRaw 批量读取原始值，按slot分组后通过管道发送MGET，返回的map只包含存在的键
+func (r *RedisCache) mgetRaw(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	groups := groupKeysBySlot(r.client, keys)
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, len(groups))
+	for i, group := range groups {
+		cmds[i] = pipe.MGet(ctx, group...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	for i, group := range groups {
+		for j, value := range cmds[i].Val() {
+			if s, ok := value.(string); ok {
+				values[group[j]] = s
+			}
+		}
+	}
+	return values, nil
+}
+
+// mgetRawTTL 批量读取原始值及其剩余生存时间，GET和PTTL在同一管道中发送，没有过期时间的键不出现在ttls中
+// 不存在的键不出现在values中，其他错误（如WRONGTYPE或连接错误）直接返回
+func (r *RedisCache) mgetRawTTL(ctx context.Context, keys []string) (map[string]string, map[string]time.Duration, error) {
+	values := make(map[string]string, len(keys))
+	ttls := make(map[string]time.Duration, len(keys))
+	if len(keys) == 0 {
+		return values, ttls, nil
+	}
+
+	pipe := r.client.Pipeline()
+	getCmds := make([]*redis.StringCmd, len(keys))
+	ttlCmds := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		getCmds[i] = pipe.Get(ctx, key)
+		ttlCmds[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, nil, err
+	}
+
+	// 管道返回的是第一个出错命令的错误，键不存在(redis.Nil)之外的错误需要逐个检查
+	for i, key := range keys {
+		value, err := getCmds[i].Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ttl, err := ttlCmds[i].Result()
+		if err != nil {
+			return nil, nil, err
+		}
+		values[key] = value
+		if ttl > 0 {
+			ttls[key] = ttl
+		}
+	}
+	return values, ttls, nil
+}
+
+// msetRaw 通过管道批量写入原始值，MSET不支持过期时间，因此每个键单独发送SET
+func (r *RedisCache) msetRaw(ctx context.Context, values map[string]string, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for key, value := range values {
+		pipe.Set(ctx, key, value, expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// encodeMap 批量编码缓存值
+func encodeMap(values map[string]interface{}, encode func(interface{}) ([]byte, error)) (map[string]string, error) {
+	encoded := make(map[string]string, len(values))
+	for key, value := range values {
+		data, err := encode(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded[key] = string(data)
+	}
+	return encoded, nil
+}
+
+// MGet 批量获取缓存，dest必须为 *map[string]T，只写入存在的键
+func (r *RedisCache) MGet(ctx context.Context, keys []string, dest interface{}) error {
+	raw, err := r.mgetRaw(ctx, keys)
+	if err != nil {
+		return err
+	}
+	return decodeMap(raw, dest, r.decode)
+}
+
+// MSet 批量设置缓存
+func (r *RedisCache) MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	encoded, err := encodeMap(values, r.encode)
+	if err != nil {
+		return err
+	}
+	return r.msetRaw(ctx, encoded, expiration)
+}
+
+// MDel 批量删除缓存，集群模式下按slot分组发送DEL
+func (r *RedisCache) MDel(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, group := range groupKeysBySlot(r.client, keys) {
+		pipe.Del(ctx, group...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
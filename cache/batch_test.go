@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestKeySlot 测试集群slot计算与hash tag，期望值与 CLUSTER KEYSLOT 的结果一致
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{"foo", 12182},
+		{"user1000", 3443},
+		{"{user1000}.following", 3443},
+		{"{user1000}.followers", 3443},
+		// 空hash tag时对整个键计算
+		{"{}foo", 9500},
+		{"foo{}{bar}", 8363},
+		// 只取第一个 { 与其后第一个 } 之间的内容
+		{"foo{{bar}}zap", 4015},
+		{"foo{bar}{zap}", 5061},
+	}
+	for _, tt := range tests {
+		if slot := keySlot(tt.key); slot != tt.slot {
+			t.Errorf("Expected slot %d for %q, got %d", tt.slot, tt.key, slot)
+		}
+	}
+}
+
+// TestGroupKeysBySlot 测试集群客户端按slot分组，其他客户端不分组
+func TestGroupKeysBySlot(t *testing.T) {
+	keys := []string{"{user1000}.following", "foo", "{user1000}.followers"}
+
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{"127.0.0.1:0"}})
+	defer cluster.Close()
+	groups := groupKeysBySlot(cluster, keys)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", groups)
+	}
+	if len(groups[0]) != 2 || groups[0][0] != "{user1000}.following" || groups[0][1] != "{user1000}.followers" {
+		t.Errorf("Expected keys with the same hash tag in one group, got %v", groups[0])
+	}
+	if len(groups[1]) != 1 || groups[1][0] != "foo" {
+		t.Errorf("Expected foo in its own group, got %v", groups[1])
+	}
+
+	single := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer single.Close()
+	if groups := groupKeysBySlot(single, keys); len(groups) != 1 || len(groups[0]) != len(keys) {
+		t.Errorf("Expected a single group for non-cluster client, got %v", groups)
+	}
+}
+
+// slotRecorder 记录管道中各个多键命令涉及的slot
+type slotRecorder struct {
+	mu    sync.Mutex
+	slots map[string][]map[int]bool
+}
+
+func (h *slotRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *slotRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *slotRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		h.mu.Lock()
+		for _, cmd := range cmds {
+			slots := make(map[int]bool)
+			for _, arg := range cmd.Args()[1:] {
+				if key, ok := arg.(string); ok {
+					slots[keySlot(key)] = true
+				}
+			}
+			h.slots[cmd.Name()] = append(h.slots[cmd.Name()], slots)
+		}
+		h.mu.Unlock()
+		return next(ctx, cmds)
+	}
+}
+
+// TestClusterBatch 测试集群模式下MGet/MSet/MDel按slot拆分命令
+// miniredis 将全部slot报告给自身，作为单节点集群使用
+func TestClusterBatch(t *testing.T) {
+	server := miniredis.RunT(t)
+	c, err := NewRedisCache(&CacheConfig{Mode: ModeCluster, Addrs: []string{server.Addr()}})
+	if err != nil {
+		t.Fatalf("Failed to create cluster cache: %v", err)
+	}
+	defer c.Close()
+	recorder := &slotRecorder{slots: make(map[string][]map[int]bool)}
+	c.client.AddHook(recorder)
+	ctx := context.Background()
+
+	values := map[string]interface{}{
+		"{user1000}.following": "a",
+		"{user1000}.followers": "b",
+		"foo":                  "c",
+	}
+	if err := c.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatalf("MSet failed: %v", err)
+	}
+	keys := []string{"{user1000}.following", "foo", "missing", "{user1000}.followers"}
+	var got map[string]string
+	if err := c.MGet(ctx, keys, &got); err != nil {
+		t.Fatalf("MGet failed: %v", err)
+	}
+	if len(got) != 3 || got["{user1000}.following"] != "a" || got["{user1000}.followers"] != "b" || got["foo"] != "c" {
+		t.Errorf("Unexpected MGet result %v", got)
+	}
+	if err := c.MDel(ctx, keys...); err != nil {
+		t.Fatalf("MDel failed: %v", err)
+	}
+	if server.Exists("foo") || server.Exists("{user1000}.following") {
+		t.Error("Expected keys to be deleted")
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for _, name := range []string{"mget", "del"} {
+		// {user1000}、foo、missing 分属三个slot
+		if len(recorder.slots[name]) != 3 {
+			t.Errorf("Expected 3 %s commands, got %d", name, len(recorder.slots[name]))
+		}
+		for _, slots := range recorder.slots[name] {
+			if len(slots) != 1 {
+				t.Errorf("Expected each %s to touch a single slot, got %v", name, slots)
+			}
+		}
+	}
+	if len(recorder.slots["set"]) != len(values) {
+		t.Errorf("Expected %d set commands, got %d", len(values), len(recorder.slots["set"]))
+	}
+}
+
+// TestMGetRawTTLErrors 测试只有键不存在被视为未命中，其他命令错误直接返回
+func TestMGetRawTTLErrors(t *testing.T) {
+	server := miniredis.RunT(t)
+	c, err := NewRedisCache(&CacheConfig{Addr: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	server.Set("str", "v")
+	server.SetTTL("str", time.Minute)
+	values, ttls, err := c.mgetRawTTL(ctx, []string{"missing", "str"})
+	if err != nil {
+		t.Fatalf("Expected no error for missing key, got %v", err)
+	}
+	if len(values) != 1 || values["str"] != "v" || ttls["str"] <= 0 {
+		t.Errorf("Unexpected result %v %v", values, ttls)
+	}
+
+	// 第一个命令返回redis.Nil时，后面的WRONGTYPE错误不能被当作未命中
+	server.HSet("hash", "f", "v")
+	if _, _, err := c.mgetRawTTL(ctx, []string{"missing", "hash"}); err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE error, got %v", err)
+	}
+}
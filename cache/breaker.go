@@ -0,0 +1,516 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+)
+
+// ErrCircuitOpen 熔断器打开时未配置降级缓存返回的错误
+var ErrCircuitOpen = errors.New("cache: circuit breaker is open")
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+const (
+	// CircuitClosed 正常访问
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen 允许少量探测请求
+	CircuitHalfOpen
+	// CircuitOpen 直接拒绝或降级
+	CircuitOpen
+)
+
+// String 状态名称，用于指标标签
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	Name             string        `mapstructure:"name"`               // 熔断器名称，用于区分多个熔断器的指标，默认default
+	FailureThreshold int           `mapstructure:"failure_threshold"`  // 连续失败多少次后打开熔断器
+	OpenTimeout      time.Duration `mapstructure:"open_timeout"`       // 打开后经过多久进入半开状态
+	HalfOpenRequests int           `mapstructure:"half_open_requests"` // 半开状态允许的探测请求数，全部成功后关闭熔断器
+}
+
+// DefaultBreakerConfig 默认熔断器配置
+func DefaultBreakerConfig() *BreakerConfig {
+	return &BreakerConfig{
+		Name:             "default",
+		FailureThreshold: 5,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 1,
+	}
+}
+
+// CircuitBreakerCache 带熔断器的缓存
+// 后端连续失败达到阈值后打开熔断器，打开期间的调用立即返回 ErrCircuitOpen，
+// 或者在配置了降级缓存（如 NoOpCache、InMemoryCache）时转发给降级缓存。
+// 只有连接、超时和连接池错误计为失败，键不存在、编解码错误、WRONGTYPE等Redis返回的错误以及调用方取消不计为失败。
+// Pipeline、TxPipeline和GetClient直接返回后端对象，不受熔断器保护。
+type CircuitBreakerCache struct {
+	cache    Cache
+	fallback Cache
+	config   BreakerConfig
+	metrics  metrics.CacheMetrics
+
+	mu         sync.Mutex
+	state      CircuitState
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+}
+
+// NewCircuitBreakerCache 创建带熔断器的缓存，fallback为nil时熔断期间返回 ErrCircuitOpen，collector为nil或未实现 metrics.CacheMetrics 时不记录指标
+func NewCircuitBreakerCache(cache Cache, config *BreakerConfig, fallback Cache, collector metrics.MetricsCollector) *CircuitBreakerCache {
+	if config == nil {
+		config = DefaultBreakerConfig()
+	}
+	c := *config
+	if c.Name == "" {
+		c.Name = "default"
+	}
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 5
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = 10 * time.Second
+	}
+	if c.HalfOpenRequests <= 0 {
+		c.HalfOpenRequests = 1
+	}
+
+	b := &CircuitBreakerCache{
+		cache:    cache,
+		fallback: fallback,
+		config:   c,
+		metrics:  cacheMetrics(collector),
+	}
+	if b.metrics != nil {
+		b.metrics.UpdateCacheCircuitState(c.Name, CircuitClosed.String())
+	}
+	return b
+}
+
+// State 获取当前熔断器状态
+func (b *CircuitBreakerCache) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkTimeout(time.Now())
+	return b.state
+}
+
+// do 在熔断器保护下执行调用
+// 调用方的ctx已取消或超时时不记录结果，避免调用方自身的截止时间打开熔断器
+func (b *CircuitBreakerCache) do(ctx context.Context, fn func(c Cache) error) error {
+	generation, ok := b.allow()
+	if !ok {
+		if b.fallback != nil {
+			return fn(b.fallback)
+		}
+		return ErrCircuitOpen
+	}
+
+	err := fn(b.cache)
+	if err != nil && ctx.Err() != nil {
+		b.release(generation)
+		return err
+	}
+	b.record(generation, isBreakerFailure(err))
+	return err
+}
+
+// release 归还未记录结果的半开探测名额
+func (b *CircuitBreakerCache) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// allow 判断是否允许访问后端，返回当前状态的代数
+func (b *CircuitBreakerCache) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.checkTimeout(time.Now())
+	switch b.state {
+	case CircuitClosed:
+		return b.generation, true
+	case CircuitHalfOpen:
+		if b.probes >= b.config.HalfOpenRequests {
+			return b.generation, false
+		}
+		b.probes++
+		return b.generation, true
+	default:
+		return b.generation, false
+	}
+}
+
+// record 记录调用结果，状态已切换时忽略旧状态下发出的请求结果
+func (b *CircuitBreakerCache) record(generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.setState(CircuitOpen, time.Now())
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.setState(CircuitOpen, time.Now())
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenRequests {
+			b.setState(CircuitClosed, time.Now())
+		}
+	}
+}
+
+// checkTimeout 打开状态超时后进入半开状态，调用方需持有锁
+func (b *CircuitBreakerCache) checkTimeout(now time.Time) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(CircuitHalfOpen, now)
+	}
+}
+
+// setState 切换状态并重置计数，调用方需持有锁
+func (b *CircuitBreakerCache) setState(state CircuitState, now time.Time) {
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == CircuitOpen {
+		b.openedAt = now
+	}
+	if b.metrics != nil {
+		b.metrics.UpdateCacheCircuitState(b.config.Name, state.String())
+	}
+}
+
+// isBreakerFailure 判断错误是否表示后端不可用
+// 只有连接、超时和连接池错误计为失败，编解码错误和Redis服务端返回的错误（如WRONGTYPE）说明连接正常；
+// 调用方ctx到期导致的错误由do排除
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, redis.ErrPoolTimeout) ||
+		errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Set 设置缓存
+func (b *CircuitBreakerCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.Set(ctx, key, value, expiration, opts...)
+	})
+}
+
+// Get 获取缓存
+func (b *CircuitBreakerCache) Get(ctx context.Context, key string, dest interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.Get(ctx, key, dest)
+	})
+}
+
+// Del 删除缓存
+func (b *CircuitBreakerCache) Del(ctx context.Context, keys ...string) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.Del(ctx, keys...)
+	})
+}
+
+// Exists 检查键是否存在
+func (b *CircuitBreakerCache) Exists(ctx context.Context, keys ...string) (result int64, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.Exists(ctx, keys...)
+		return err
+	})
+	return result, err
+}
+
+// Expire 设置过期时间
+func (b *CircuitBreakerCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.Expire(ctx, key, expiration)
+	})
+}
+
+// TTL 获取剩余生存时间
+func (b *CircuitBreakerCache) TTL(ctx context.Context, key string) (result time.Duration, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.TTL(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// MGet 批量获取缓存
+func (b *CircuitBreakerCache) MGet(ctx context.Context, keys []string, dest interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.MGet(ctx, keys, dest)
+	})
+}
+
+// MSet 批量设置缓存
+func (b *CircuitBreakerCache) MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.MSet(ctx, values, expiration)
+	})
+}
+
+// MDel 批量删除缓存
+func (b *CircuitBreakerCache) MDel(ctx context.Context, keys ...string) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.MDel(ctx, keys...)
+	})
+}
+
+// InvalidateTags 删除标签下的所有缓存键
+func (b *CircuitBreakerCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.InvalidateTags(ctx, tags...)
+	})
+}
+
+// SetString 设置字符串值
+func (b *CircuitBreakerCache) SetString(ctx context.Context, key, value string, expiration time.Duration) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.SetString(ctx, key, value, expiration)
+	})
+}
+
+// GetString 获取字符串值
+func (b *CircuitBreakerCache) GetString(ctx context.Context, key string) (result string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.GetString(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// Incr 递增
+func (b *CircuitBreakerCache) Incr(ctx context.Context, key string) (result int64, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.Incr(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// Decr 递减
+func (b *CircuitBreakerCache) Decr(ctx context.Context, key string) (result int64, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.Decr(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// HSet 设置哈希字段
+func (b *CircuitBreakerCache) HSet(ctx context.Context, key string, values ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.HSet(ctx, key, values...)
+	})
+}
+
+// HGet 获取哈希字段值
+func (b *CircuitBreakerCache) HGet(ctx context.Context, key, field string) (result string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.HGet(ctx, key, field)
+		return err
+	})
+	return result, err
+}
+
+// HGetAll 获取所有哈希字段
+func (b *CircuitBreakerCache) HGetAll(ctx context.Context, key string) (result map[string]string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.HGetAll(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// HDel 删除哈希字段
+func (b *CircuitBreakerCache) HDel(ctx context.Context, key string, fields ...string) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.HDel(ctx, key, fields...)
+	})
+}
+
+// LPush 从左侧推入列表
+func (b *CircuitBreakerCache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.LPush(ctx, key, values...)
+	})
+}
+
+// RPush 从右侧推入列表
+func (b *CircuitBreakerCache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.RPush(ctx, key, values...)
+	})
+}
+
+// LPop 从左侧弹出列表元素
+func (b *CircuitBreakerCache) LPop(ctx context.Context, key string) (result string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.LPop(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// RPop 从右侧弹出列表元素
+func (b *CircuitBreakerCache) RPop(ctx context.Context, key string) (result string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.RPop(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// LLen 获取列表长度
+func (b *CircuitBreakerCache) LLen(ctx context.Context, key string) (result int64, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.LLen(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// SAdd 添加集合成员
+func (b *CircuitBreakerCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.SAdd(ctx, key, members...)
+	})
+}
+
+// SRem 移除集合成员
+func (b *CircuitBreakerCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.SRem(ctx, key, members...)
+	})
+}
+
+// SMembers 获取集合所有成员
+func (b *CircuitBreakerCache) SMembers(ctx context.Context, key string) (result []string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.SMembers(ctx, key)
+		return err
+	})
+	return result, err
+}
+
+// SIsMember 检查是否为集合成员
+func (b *CircuitBreakerCache) SIsMember(ctx context.Context, key string, member interface{}) (result bool, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.SIsMember(ctx, key, member)
+		return err
+	})
+	return result, err
+}
+
+// ZAdd 添加有序集合成员
+func (b *CircuitBreakerCache) ZAdd(ctx context.Context, key string, members ...redis.Z) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.ZAdd(ctx, key, members...)
+	})
+}
+
+// ZRem 移除有序集合成员
+func (b *CircuitBreakerCache) ZRem(ctx context.Context, key string, members ...interface{}) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.ZRem(ctx, key, members...)
+	})
+}
+
+// ZRange 获取有序集合范围内的成员
+func (b *CircuitBreakerCache) ZRange(ctx context.Context, key string, start, stop int64) (result []string, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.ZRange(ctx, key, start, stop)
+		return err
+	})
+	return result, err
+}
+
+// ZRangeWithScores 获取有序集合范围内的成员及分数
+func (b *CircuitBreakerCache) ZRangeWithScores(ctx context.Context, key string, start, stop int64) (result []redis.Z, err error) {
+	err = b.do(ctx, func(c Cache) (err error) {
+		result, err = c.ZRangeWithScores(ctx, key, start, stop)
+		return err
+	})
+	return result, err
+}
+
+// Ping 测试连接，熔断期间用作探测请求
+func (b *CircuitBreakerCache) Ping(ctx context.Context) error {
+	return b.do(ctx, func(c Cache) error {
+		return c.Ping(ctx)
+	})
+}
+
+// Pipeline 获取后端管道，不受熔断器保护
+func (b *CircuitBreakerCache) Pipeline() redis.Pipeliner {
+	return b.cache.Pipeline()
+}
+
+// TxPipeline 获取后端事务管道，不受熔断器保护
+func (b *CircuitBreakerCache) TxPipeline() redis.Pipeliner {
+	return b.cache.TxPipeline()
+}
+
+// Close 关闭后端缓存和降级缓存
+func (b *CircuitBreakerCache) Close() error {
+	err := b.cache.Close()
+	if b.fallback != nil {
+		if fallbackErr := b.fallback.Close(); err == nil {
+			err = fallbackErr
+		}
+	}
+	return err
+}
+
+// GetClient 获取后端原始Redis客户端
+func (b *CircuitBreakerCache) GetClient() redis.UniversalClient {
+	return b.cache.GetClient()
+}
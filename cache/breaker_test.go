@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// flakyCache 可控制是否失败的缓存
+type flakyCache struct {
+	NoOpCache
+	mu    sync.Mutex
+	err   error
+	calls int
+}
+
+func (f *flakyCache) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *flakyCache) GetString(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return "value", nil
+}
+
+// TestCircuitBreakerOpensAndRecovers 测试熔断器打开、半开探测与恢复
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	backend := &flakyCache{}
+	breaker := NewCircuitBreakerCache(backend, &BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      50 * time.Millisecond,
+	}, nil, nil)
+	ctx := context.Background()
+
+	backend.setErr(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	for i := 0; i < 3; i++ {
+		breaker.GetString(ctx, "k")
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected circuit to be open, got %s", breaker.State())
+	}
+
+	if _, err := breaker.GetString(ctx, "k"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if backend.calls != 3 {
+		t.Errorf("Expected open circuit to skip backend, got %d calls", backend.calls)
+	}
+
+	// 半开探测失败后重新打开
+	time.Sleep(60 * time.Millisecond)
+	if breaker.State() != CircuitHalfOpen {
+		t.Fatalf("Expected circuit to be half-open, got %s", breaker.State())
+	}
+	breaker.GetString(ctx, "k")
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected failed probe to reopen circuit, got %s", breaker.State())
+	}
+
+	// 半开探测成功后关闭
+	time.Sleep(60 * time.Millisecond)
+	backend.setErr(nil)
+	if value, err := breaker.GetString(ctx, "k"); err != nil || value != "value" {
+		t.Errorf("Expected probe to reach backend, got %q (err=%v)", value, err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected circuit to be closed, got %s", breaker.State())
+	}
+}
+
+// TestCircuitBreakerIgnoresNotFound 测试键不存在不计为失败
+func TestCircuitBreakerIgnoresNotFound(t *testing.T) {
+	backend := &flakyCache{}
+	backend.setErr(ErrNotFound)
+	breaker := NewCircuitBreakerCache(backend, &BreakerConfig{FailureThreshold: 1}, nil, nil)
+
+	for i := 0; i < 3; i++ {
+		breaker.GetString(context.Background(), "k")
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected circuit to stay closed, got %s", breaker.State())
+	}
+}
+
+// TestCircuitBreakerIgnoresClientErrors 测试编解码错误和服务端错误不计为失败
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	var dest int
+	decodeErr := json.Unmarshal([]byte(`"value"`), &dest)
+	errs := []error{
+		fmt.Errorf("failed to unmarshal value: %w", decodeErr),
+		errors.New("protobuf codec requires proto.Message"),
+		redis.Nil,
+		context.Canceled,
+	}
+
+	backend := &flakyCache{}
+	breaker := NewCircuitBreakerCache(backend, &BreakerConfig{FailureThreshold: 1}, nil, nil)
+	for _, err := range errs {
+		backend.setErr(err)
+		breaker.GetString(context.Background(), "k")
+		if breaker.State() != CircuitClosed {
+			t.Errorf("Expected %v not to open circuit, got %s", err, breaker.State())
+		}
+	}
+
+	for _, err := range []error{
+		&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET},
+		redis.ErrPoolTimeout,
+		io.EOF,
+	} {
+		if !isBreakerFailure(fmt.Errorf("wrapped: %w", err)) {
+			t.Errorf("Expected %v to count as backend failure", err)
+		}
+	}
+}
+
+// TestCircuitBreakerIgnoresCallerDeadline 测试调用方ctx到期不打开熔断器，也不占用半开探测名额
+func TestCircuitBreakerIgnoresCallerDeadline(t *testing.T) {
+	backend := &flakyCache{}
+	breaker := NewCircuitBreakerCache(backend, &BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond}, nil, nil)
+	backend.setErr(context.DeadlineExceeded)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		if _, err := breaker.GetString(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded, got %v", err)
+		}
+		cancel()
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("Expected caller deadline not to open circuit, got %s", breaker.State())
+	}
+
+	breaker = NewCircuitBreakerCache(backend, &BreakerConfig{FailureThreshold: 1, OpenTimeout: 20 * time.Millisecond}, nil, nil)
+	backend.setErr(io.EOF)
+	breaker.GetString(context.Background(), "k")
+	time.Sleep(30 * time.Millisecond)
+
+	backend.setErr(context.DeadlineExceeded)
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	breaker.GetString(expired, "k")
+	backend.setErr(nil)
+	if _, err := breaker.GetString(context.Background(), "k"); err != nil {
+		t.Errorf("Expected probe slot to be released, got %v", err)
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected circuit to close after probe, got %s", breaker.State())
+	}
+}
+
+// TestCircuitBreakerFallback 测试熔断期间使用降级缓存
+func TestCircuitBreakerFallback(t *testing.T) {
+	backend := &flakyCache{}
+	backend.setErr(context.DeadlineExceeded)
+	fallback := NewInMemoryCache()
+	breaker := NewCircuitBreakerCache(backend, &BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}, fallback, nil)
+	defer breaker.Close()
+	ctx := context.Background()
+
+	breaker.GetString(ctx, "k")
+	if err := breaker.SetString(ctx, "k", "local", 0); err != nil {
+		t.Fatalf("Expected fallback to accept writes, got %v", err)
+	}
+	if value, err := breaker.GetString(ctx, "k"); err != nil || value != "local" {
+		t.Errorf("Expected fallback value 'local', got %q (err=%v)", value, err)
+	}
+}
@@ -2,35 +2,88 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound 键不存在错误，所有Cache实现在键（或列表元素、哈希字段）不存在时统一返回
+var ErrNotFound = errors.New("cache: key not found")
+
+// Redis部署模式
+const (
+	// ModeSingle 单节点
+	ModeSingle = "single"
+	// ModeCluster Redis Cluster
+	ModeCluster = "cluster"
+	// ModeSentinel 哨兵故障转移
+	ModeSentinel = "sentinel"
+)
+
+// 读请求路由方式，仅在集群和哨兵模式下生效
+const (
+	// ReadFromMaster 只从主节点读取
+	ReadFromMaster = "master"
+	// ReadFromReplica 只从从节点读取
+	ReadFromReplica = "replica"
+	// ReadByLatency 路由到延迟最低的节点
+	ReadByLatency = "latency"
+	// ReadRandomly 随机路由到主节点或从节点
+	ReadRandomly = "random"
 )
 
 // CacheConfig Redis缓存配置
 type CacheConfig struct {
-	Addr         string        `mapstructure:"addr"`
-	Password     string        `mapstructure:"password"`
-	DB           int           `mapstructure:"db"`
-	PoolSize     int           `mapstructure:"pool_size"`
-	MinIdleConns int           `mapstructure:"min_idle_conns"`
-	DialTimeout  time.Duration `mapstructure:"dial_timeout"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	Name             string        `mapstructure:"name"`              // 实例名称，用于区分多个实例的连接池指标，默认default
+	Mode             string        `mapstructure:"mode"`              // 部署模式: single(默认), cluster, sentinel
+	Addr             string        `mapstructure:"addr"`              // 单节点地址
+	Addrs            []string      `mapstructure:"addrs"`             // 集群节点或哨兵地址
+	MasterName       string        `mapstructure:"master_name"`       // 哨兵模式下的主节点名称
+	SentinelPassword string        `mapstructure:"sentinel_password"` // 哨兵密码
+	ReadMode         string        `mapstructure:"read_mode"`         // 读请求路由: master(默认), replica, latency, random
+	Username         string        `mapstructure:"username"`          // ACL用户名
+	Password         string        `mapstructure:"password"`
+	DB               int           `mapstructure:"db"`
+	PoolSize         int           `mapstructure:"pool_size"`
+	MinIdleConns     int           `mapstructure:"min_idle_conns"`
+	DialTimeout      time.Duration `mapstructure:"dial_timeout"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	TLS              *TLSConfig    `mapstructure:"tls"`
+
+	Codec                string `mapstructure:"codec"`                 // 值编解码器: json(默认), msgpack, protobuf, gob
+	Compression          string `mapstructure:"compression"`           // 压缩算法: none(默认), gzip, snappy, zstd
+	CompressionThreshold int    `mapstructure:"compression_threshold"` // 压缩阈值(字节)，默认1024
+
+	SlowThreshold     time.Duration `mapstructure:"slow_threshold"`      // 慢命令日志阈值，0表示不记录
+	PoolStatsInterval time.Duration `mapstructure:"pool_stats_interval"` // 连接池指标上报间隔，默认15秒
+	Tracing           bool          `mapstructure:"tracing"`             // 是否为命令创建Sentry子span
+	LazyConnect       bool          `mapstructure:"lazy_connect"`        // 创建时不检查连接，Redis暂时不可用时命令返回错误，恢复后自动重连
 }
 
 // Cache 缓存接口
 type Cache interface {
 	// 基础操作
-	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error
 	Get(ctx context.Context, key string, dest interface{}) error
 	Del(ctx context.Context, keys ...string) error
 	Exists(ctx context.Context, keys ...string) (int64, error)
 	Expire(ctx context.Context, key string, expiration time.Duration) error
 	TTL(ctx context.Context, key string) (time.Duration, error)
 
+	// 批量操作，集群模式下按slot分组并通过管道发送
+	MGet(ctx context.Context, keys []string, dest interface{}) error
+	MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error
+	MDel(ctx context.Context, keys ...string) error
+
+	// 标签操作，通过 WithTags 关联的键会在标签失效时一并删除
+	InvalidateTags(ctx context.Context, tags ...string) error
+
 	// 字符串操作
 	SetString(ctx context.Context, key, value string, expiration time.Duration) error
 	GetString(ctx context.Context, key string) (string, error)
@@ -72,24 +125,37 @@ type Cache interface {
 	Ping(ctx context.Context) error
 	Close() error
 
-	// 获取原始客户端
-	GetClient() *redis.Client
+	// 获取原始客户端，单节点、集群和哨兵模式下分别为 *redis.Client、*redis.ClusterClient 等实现
+	GetClient() redis.UniversalClient
 }
 
 // RedisCache Redis缓存实现
 type RedisCache struct {
-	client *redis.Client
-	config *CacheConfig
+	client     redis.UniversalClient
+	config     *CacheConfig
+	serializer *serializer
+	metrics    metrics.CacheMetrics
+	logger     *zap.Logger
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
 }
 
 // NewRedisCache 创建Redis缓存实例
-func NewRedisCache(config *CacheConfig) (*RedisCache, error) {
+// 通过 WithMetrics 记录命令耗时、命中率和连接池指标，通过 WithLogger 记录超过 SlowThreshold 的慢命令
+func NewRedisCache(config *CacheConfig, opts ...Option) (*RedisCache, error) {
 	if config == nil {
 		return nil, fmt.Errorf("cache config is required")
 	}
 
 	// 设置默认值
-	if config.Addr == "" {
+	if config.Mode == "" {
+		config.Mode = ModeSingle
+	}
+	if config.ReadMode == "" {
+		config.ReadMode = ReadFromMaster
+	}
+	if config.Addr == "" && len(config.Addrs) == 0 {
 		config.Addr = "localhost:6379"
 	}
 	if config.PoolSize == 0 {
@@ -107,48 +173,155 @@ func NewRedisCache(config *CacheConfig) (*RedisCache, error) {
 	if config.WriteTimeout == 0 {
 		config.WriteTimeout = 3 * time.Second
 	}
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if config.PoolStatsInterval == 0 {
+		config.PoolStatsInterval = 15 * time.Second
+	}
+
+	serializer, err := newSerializer(config.Codec, config.Compression, config.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:         config.Addr,
-		Password:     config.Password,
-		DB:           config.DB,
-		PoolSize:     config.PoolSize,
-		MinIdleConns: config.MinIdleConns,
-		DialTimeout:  config.DialTimeout,
-		ReadTimeout:  config.ReadTimeout,
-		WriteTimeout: config.WriteTimeout,
+	client, err := newUniversalClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	o := applyOptions(opts)
+	r := &RedisCache{
+		client:     client,
+		config:     config,
+		serializer: serializer,
+		metrics:    cacheMetrics(o.metrics),
+		logger:     o.logger,
+	}
+	client.AddHook(&instrumentationHook{
+		metrics:       r.metrics,
+		logger:        r.logger,
+		slowThreshold: config.SlowThreshold,
+		tracing:       config.Tracing,
 	})
 
 	// 测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	if !config.LazyConnect {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		}
+	}
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	if r.metrics != nil {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.reportPoolStats(config.PoolStatsInterval)
 	}
 
-	return &RedisCache{
-		client: client,
-		config: config,
-	}, nil
+	return r, nil
+}
+
+// newUniversalClient 根据部署模式创建Redis客户端
+func newUniversalClient(config *CacheConfig) (redis.UniversalClient, error) {
+	addrs := config.Addrs
+	if len(addrs) == 0 && config.Addr != "" {
+		addrs = []string{config.Addr}
+	}
+
+	switch config.ReadMode {
+	case ReadFromMaster, ReadFromReplica, ReadByLatency, ReadRandomly:
+	default:
+		return nil, fmt.Errorf("unsupported redis read mode: %s", config.ReadMode)
+	}
+
+	tlsConfig, err := loadTLSConfig(config.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Mode {
+	case ModeSingle:
+		return redis.NewClient(&redis.Options{
+			Addr:         config.Addr,
+			Username:     config.Username,
+			Password:     config.Password,
+			DB:           config.DB,
+			PoolSize:     config.PoolSize,
+			MinIdleConns: config.MinIdleConns,
+			DialTimeout:  config.DialTimeout,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+			TLSConfig:    tlsConfig,
+		}), nil
+	case ModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:          addrs,
+			Username:       config.Username,
+			Password:       config.Password,
+			ReadOnly:       config.ReadMode != ReadFromMaster,
+			RouteByLatency: config.ReadMode == ReadByLatency,
+			RouteRandomly:  config.ReadMode == ReadRandomly,
+			PoolSize:       config.PoolSize,
+			MinIdleConns:   config.MinIdleConns,
+			DialTimeout:    config.DialTimeout,
+			ReadTimeout:    config.ReadTimeout,
+			WriteTimeout:   config.WriteTimeout,
+			TLSConfig:      tlsConfig,
+		}), nil
+	case ModeSentinel:
+		if config.MasterName == "" {
+			return nil, fmt.Errorf("master_name is required in sentinel mode")
+		}
+		options := &redis.FailoverOptions{
+			MasterName:       config.MasterName,
+			SentinelAddrs:    addrs,
+			SentinelPassword: config.SentinelPassword,
+			Username:         config.Username,
+			Password:         config.Password,
+			DB:               config.DB,
+			ReplicaOnly:      config.ReadMode == ReadFromReplica,
+			RouteByLatency:   config.ReadMode == ReadByLatency,
+			RouteRandomly:    config.ReadMode == ReadRandomly,
+			PoolSize:         config.PoolSize,
+			MinIdleConns:     config.MinIdleConns,
+			DialTimeout:      config.DialTimeout,
+			ReadTimeout:      config.ReadTimeout,
+			WriteTimeout:     config.WriteTimeout,
+			TLSConfig:        tlsConfig,
+		}
+		// 从节点读取需要故障转移集群客户端，写请求仍然路由到主节点
+		if config.ReadMode != ReadFromMaster {
+			return redis.NewFailoverClusterClient(options), nil
+		}
+		return redis.NewFailoverClient(options), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", config.Mode)
+	}
 }
 
 // Set 设置缓存
-func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	data, err := json.Marshal(value)
+func (r *RedisCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
+	data, err := r.encode(value)
 	if err != nil {
-		return fmt.Errorf("failed to marshal value: %w", err)
+		return err
+	}
+	if options := applySetOptions(opts); len(options.tags) > 0 {
+		return r.setTagged(ctx, key, data, expiration, options.tags)
 	}
 	return r.client.Set(ctx, key, data, expiration).Err()
 }
 
 // Get 获取缓存
 func (r *RedisCache) Get(ctx context.Context, key string, dest interface{}) error {
-	data, err := r.client.Get(ctx, key).Result()
+	data, err := r.client.Get(ctx, key).Bytes()
 	if err != nil {
-		return err
+		return normalizeError(err)
 	}
-	return json.Unmarshal([]byte(data), dest)
+	return r.decode(data, dest)
 }
 
 // Del 删除缓存
@@ -178,7 +351,8 @@ func (r *RedisCache) SetString(ctx context.Context, key, value string, expiratio
 
 // GetString 获取字符串值
 func (r *RedisCache) GetString(ctx context.Context, key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	value, err := r.client.Get(ctx, key).Result()
+	return value, normalizeError(err)
 }
 
 // Incr 递增
@@ -198,7 +372,8 @@ func (r *RedisCache) HSet(ctx context.Context, key string, values ...interface{}
 
 // HGet 获取哈希字段值
 func (r *RedisCache) HGet(ctx context.Context, key, field string) (string, error) {
-	return r.client.HGet(ctx, key, field).Result()
+	value, err := r.client.HGet(ctx, key, field).Result()
+	return value, normalizeError(err)
 }
 
 // HGetAll 获取所有哈希字段
@@ -223,12 +398,14 @@ func (r *RedisCache) RPush(ctx context.Context, key string, values ...interface{
 
 // LPop 从左侧弹出列表元素
 func (r *RedisCache) LPop(ctx context.Context, key string) (string, error) {
-	return r.client.LPop(ctx, key).Result()
+	value, err := r.client.LPop(ctx, key).Result()
+	return value, normalizeError(err)
 }
 
 // RPop 从右侧弹出列表元素
 func (r *RedisCache) RPop(ctx context.Context, key string) (string, error) {
-	return r.client.RPop(ctx, key).Result()
+	value, err := r.client.RPop(ctx, key).Result()
+	return value, normalizeError(err)
 }
 
 // LLen 获取列表长度
@@ -293,20 +470,44 @@ func (r *RedisCache) Ping(ctx context.Context) error {
 
 // Close 关闭连接
 func (r *RedisCache) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+			<-r.done
+		}
+	})
 	return r.client.Close()
 }
 
 // GetClient 获取原始Redis客户端
-func (r *RedisCache) GetClient() *redis.Client {
+func (r *RedisCache) GetClient() redis.UniversalClient {
 	return r.client
 }
 
+// encode 按配置的编解码器和压缩算法编码缓存值
+func (r *RedisCache) encode(value interface{}) ([]byte, error) {
+	return r.serializer.encode(value)
+}
+
+// decode 根据值的头部字节解码缓存值
+func (r *RedisCache) decode(data []byte, dest interface{}) error {
+	return r.serializer.decode(data, dest)
+}
+
+// normalizeError 将redis.Nil转换为ErrNotFound
+func normalizeError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return ErrNotFound
+	}
+	return err
+}
+
 // 全局缓存实例
 var globalCache Cache
 
 // InitGlobalCache 初始化全局缓存
-func InitGlobalCache(config *CacheConfig) error {
-	cache, err := NewRedisCache(config)
+func InitGlobalCache(config *CacheConfig, opts ...Option) error {
+	cache, err := NewRedisCache(config, opts...)
 	if err != nil {
 		return err
 	}
@@ -322,11 +523,11 @@ func GetGlobalCache() Cache {
 // 便捷函数
 
 // Set 设置缓存的便捷函数
-func Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+func Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
 	if globalCache == nil {
 		return fmt.Errorf("global cache not initialized")
 	}
-	return globalCache.Set(ctx, key, value, expiration)
+	return globalCache.Set(ctx, key, value, expiration, opts...)
 }
 
 // Get 获取缓存的便捷函数
@@ -370,13 +571,13 @@ func NewNoOpCache() Cache {
 }
 
 // Set 空操作
-func (n *NoOpCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+func (n *NoOpCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
 	return nil
 }
 
 // Get 空操作，总是返回未找到错误
 func (n *NoOpCache) Get(ctx context.Context, key string, dest interface{}) error {
-	return fmt.Errorf("key not found")
+	return ErrNotFound
 }
 
 // Del 空操作
@@ -399,6 +600,26 @@ func (n *NoOpCache) TTL(ctx context.Context, key string) (time.Duration, error)
 	return -1, nil
 }
 
+// InvalidateTags 空操作
+func (n *NoOpCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return nil
+}
+
+// MGet 空操作，不写入任何键
+func (n *NoOpCache) MGet(ctx context.Context, keys []string, dest interface{}) error {
+	return nil
+}
+
+// MSet 空操作
+func (n *NoOpCache) MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	return nil
+}
+
+// MDel 空操作
+func (n *NoOpCache) MDel(ctx context.Context, keys ...string) error {
+	return nil
+}
+
 // SetString 空操作
 func (n *NoOpCache) SetString(ctx context.Context, key, value string, expiration time.Duration) error {
 	return nil
@@ -406,7 +627,7 @@ func (n *NoOpCache) SetString(ctx context.Context, key, value string, expiration
 
 // GetString 空操作，总是返回空字符串和未找到错误
 func (n *NoOpCache) GetString(ctx context.Context, key string) (string, error) {
-	return "", fmt.Errorf("key not found")
+	return "", ErrNotFound
 }
 
 // Incr 空操作，总是返回1
@@ -426,7 +647,7 @@ func (n *NoOpCache) HSet(ctx context.Context, key string, values ...interface{})
 
 // HGet 空操作，总是返回空字符串和未找到错误
 func (n *NoOpCache) HGet(ctx context.Context, key, field string) (string, error) {
-	return "", fmt.Errorf("key not found")
+	return "", ErrNotFound
 }
 
 // HGetAll 空操作，总是返回空map
@@ -451,12 +672,12 @@ func (n *NoOpCache) RPush(ctx context.Context, key string, values ...interface{}
 
 // LPop 空操作，总是返回空字符串和未找到错误
 func (n *NoOpCache) LPop(ctx context.Context, key string) (string, error) {
-	return "", fmt.Errorf("key not found")
+	return "", ErrNotFound
 }
 
 // RPop 空操作，总是返回空字符串和未找到错误
 func (n *NoOpCache) RPop(ctx context.Context, key string) (string, error) {
-	return "", fmt.Errorf("key not found")
+	return "", ErrNotFound
 }
 
 // LLen 空操作，总是返回0
@@ -525,6 +746,6 @@ func (n *NoOpCache) Close() error {
 }
 
 // GetClient 空操作，返回nil
-func (n *NoOpCache) GetClient() *redis.Client {
+func (n *NoOpCache) GetClient() redis.UniversalClient {
 	return nil
 }
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestNewUniversalClientModes 测试不同部署模式创建的客户端类型
+func TestNewUniversalClientModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CacheConfig
+		check  func(redis.UniversalClient) bool
+	}{
+		{
+			name:   "single",
+			config: CacheConfig{Mode: ModeSingle, ReadMode: ReadFromMaster, Addr: "localhost:6379"},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			name:   "cluster",
+			config: CacheConfig{Mode: ModeCluster, ReadMode: ReadByLatency, Addrs: []string{"localhost:7000", "localhost:7001"}},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.ClusterClient); return ok },
+		},
+		{
+			name:   "sentinel",
+			config: CacheConfig{Mode: ModeSentinel, ReadMode: ReadFromMaster, MasterName: "mymaster", Addrs: []string{"localhost:26379"}},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			name:   "sentinel replica",
+			config: CacheConfig{Mode: ModeSentinel, ReadMode: ReadFromReplica, MasterName: "mymaster", Addrs: []string{"localhost:26379"}},
+			check:  func(c redis.UniversalClient) bool { _, ok := c.(*redis.ClusterClient); return ok },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newUniversalClient(&tt.config)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer client.Close()
+			if !tt.check(client) {
+				t.Errorf("Unexpected client type %T", client)
+			}
+		})
+	}
+}
+
+// TestNewUniversalClientInvalidConfig 测试无效配置
+func TestNewUniversalClientInvalidConfig(t *testing.T) {
+	invalid := []CacheConfig{
+		{Mode: "unknown", ReadMode: ReadFromMaster},
+		{Mode: ModeSingle, ReadMode: "unknown"},
+		{Mode: ModeSentinel, ReadMode: ReadFromMaster, Addrs: []string{"localhost:26379"}},
+	}
+	for _, config := range invalid {
+		if _, err := newUniversalClient(&config); err == nil {
+			t.Errorf("Expected error for config %+v", config)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// 编解码器名称
+const (
+	CodecJSON     = "json"
+	CodecMsgpack  = "msgpack"
+	CodecProtobuf = "protobuf"
+	CodecGob      = "gob"
+)
+
+// 压缩算法名称
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// DefaultCompressionThreshold 默认压缩阈值，编码后小于该字节数的值不压缩
+const DefaultCompressionThreshold = 1024
+
+// Codec 缓存值编解码器
+type Codec interface {
+	// Name 编解码器名称
+	Name() string
+	// Marshal 编码
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 解码
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonCodec JSON编解码器
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return CodecJSON }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec MessagePack编解码器
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                               { return CodecMsgpack }
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+// protobufCodec Protobuf编解码器，值必须实现proto.Message，不支持Loader等对值再做封装的场景
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string { return CodecProtobuf }
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+	}
+	return proto.Marshal(message)
+}
+
+// Unmarshal 解码，v为指向消息指针的指针(如Typed[*pb.Msg]传入的**pb.Msg)时解码到其指向的消息，nil时创建新消息
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if message, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, message)
+	}
+
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() == reflect.Pointer && !ptr.IsNil() && ptr.Elem().Kind() == reflect.Pointer {
+		elem := ptr.Elem()
+		if elem.Type().Implements(protoMessageType) {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+			return proto.Unmarshal(data, elem.Interface().(proto.Message))
+		}
+	}
+	return fmt.Errorf("protobuf codec: %T does not implement proto.Message", v)
+}
+
+// protoMessageType proto.Message接口类型
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// gobCodec Gob编解码器
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return CodecGob }
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// 头部字节中的编解码器和压缩算法编号，写入后不可修改
+var (
+	codecIDs = map[string]byte{
+		CodecJSON:     1,
+		CodecMsgpack:  2,
+		CodecProtobuf: 3,
+		CodecGob:      4,
+	}
+	codecsByID = map[byte]Codec{
+		1: jsonCodec{},
+		2: msgpackCodec{},
+		3: protobufCodec{},
+		4: gobCodec{},
+	}
+	compressionIDs = map[string]byte{
+		CompressionNone:   0,
+		CompressionGzip:   1,
+		CompressionSnappy: 2,
+		CompressionZstd:   3,
+	}
+)
+
+// NewCodec 根据名称获取编解码器，名称为空时返回JSON编解码器
+func NewCodec(name string) (Codec, error) {
+	if name == "" {
+		name = CodecJSON
+	}
+	id, ok := codecIDs[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cache codec: %s", name)
+	}
+	return codecsByID[id], nil
+}
+
+// 头部字节格式: 1ccc kkkk，最高位为标记位，ccc为压缩算法编号，kkkk为编解码器编号。
+// 最高位为0的值视为未带头部的JSON数据，按JSON解码（JSON文本的首字节总是ASCII字符）。
+// 未压缩的JSON值写入时不带头部。
+const headerFlag = 0x80
+
+// serializer 缓存值序列化器，负责编码、压缩和头部字节
+type serializer struct {
+	codec       Codec
+	codecID     byte
+	compression byte
+	threshold   int
+}
+
+// defaultSerializer 默认序列化器：JSON编码，不压缩
+var defaultSerializer = &serializer{codec: jsonCodec{}, codecID: codecIDs[CodecJSON]}
+
+// newSerializer 根据配置创建序列化器
+func newSerializer(codecName, compressionName string, threshold int) (*serializer, error) {
+	codec, err := NewCodec(codecName)
+	if err != nil {
+		return nil, err
+	}
+	if compressionName == "" {
+		compressionName = CompressionNone
+	}
+	compression, ok := compressionIDs[compressionName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cache compression: %s", compressionName)
+	}
+	if threshold <= 0 {
+		threshold = DefaultCompressionThreshold
+	}
+	return &serializer{
+		codec:       codec,
+		codecID:     codecIDs[codec.Name()],
+		compression: compression,
+		threshold:   threshold,
+	}, nil
+}
+
+// encode 编码缓存值，超过阈值时压缩，并写入头部字节
+// 未压缩的JSON值不写头部，保持与旧版本及其他直接读取Redis的服务兼容，INCR等命令也可直接作用于数字值
+func (s *serializer) encode(value interface{}) ([]byte, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	compression := byte(0)
+	if s.compression != 0 && len(data) >= s.threshold {
+		compressed, err := compress(s.compression, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress value: %w", err)
+		}
+		// 压缩后没有变小时保存原始数据
+		if len(compressed) < len(data) {
+			data = compressed
+			compression = s.compression
+		}
+	}
+
+	if compression == 0 && s.codecID == codecIDs[CodecJSON] {
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(data)+1)
+	out = append(out, headerFlag|compression<<4|s.codecID)
+	return append(out, data...), nil
+}
+
+// decode 根据头部字节解码缓存值，与当前配置的编解码器无关
+func (s *serializer) decode(data []byte, dest interface{}) error {
+	if len(data) == 0 || data[0]&headerFlag == 0 {
+		return json.Unmarshal(data, dest)
+	}
+
+	header := data[0]
+	codec, ok := codecsByID[header&0x0f]
+	if !ok {
+		return fmt.Errorf("unknown cache codec id: %d", header&0x0f)
+	}
+
+	payload := data[1:]
+	if compression := (header >> 4) & 0x07; compression != 0 {
+		var err error
+		if payload, err = decompress(compression, payload); err != nil {
+			return fmt.Errorf("failed to decompress value: %w", err)
+		}
+	}
+	return codec.Unmarshal(payload, dest)
+}
+
+// zstd编码器和解码器可并发使用，全局共享
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// compress 压缩数据
+func compress(compression byte, data []byte) ([]byte, error) {
+	switch compression {
+	case compressionIDs[CompressionGzip]:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case compressionIDs[CompressionSnappy]:
+		return snappy.Encode(nil, data), nil
+	case compressionIDs[CompressionZstd]:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression id: %d", compression)
+	}
+}
+
+// decompress 解压数据
+func decompress(compression byte, data []byte) ([]byte, error) {
+	switch compression {
+	case compressionIDs[CompressionGzip]:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	case compressionIDs[CompressionSnappy]:
+		return snappy.Decode(nil, data)
+	case compressionIDs[CompressionZstd]:
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unknown compression id: %d", compression)
+	}
+}
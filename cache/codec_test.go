@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type codecPayload struct {
+	Name  string
+	Count int
+	Tags  []string
+}
+
+// TestSerializerRoundTrip 测试各编解码器与压缩算法组合的编解码
+func TestSerializerRoundTrip(t *testing.T) {
+	value := codecPayload{Name: strings.Repeat("x", 2048), Count: 42, Tags: []string{"a", "b"}}
+
+	for _, codec := range []string{CodecJSON, CodecMsgpack, CodecGob} {
+		for _, compression := range []string{CompressionNone, CompressionGzip, CompressionSnappy, CompressionZstd} {
+			s, err := newSerializer(codec, compression, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			data, err := s.encode(value)
+			if err != nil {
+				t.Fatalf("%s/%s: failed to encode: %v", codec, compression, err)
+			}
+			if compression != CompressionNone && len(data) >= 2048 {
+				t.Errorf("%s/%s: expected compressed value, got %d bytes", codec, compression, len(data))
+			}
+
+			var got codecPayload
+			if err := s.decode(data, &got); err != nil {
+				t.Fatalf("%s/%s: failed to decode: %v", codec, compression, err)
+			}
+			if got.Name != value.Name || got.Count != value.Count || len(got.Tags) != 2 {
+				t.Errorf("%s/%s: unexpected value %+v", codec, compression, got)
+			}
+		}
+	}
+}
+
+// TestSerializerProtobuf 测试Protobuf编解码
+func TestSerializerProtobuf(t *testing.T) {
+	s, err := newSerializer(CodecProtobuf, CompressionNone, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	data, err := s.encode(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	got := &wrapperspb.StringValue{}
+	if err := s.decode(data, got); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got.GetValue() != "hello" {
+		t.Errorf("Expected 'hello', got %q", got.GetValue())
+	}
+
+	if _, err := s.encode("not a message"); err == nil {
+		t.Error("Expected error for non-proto value")
+	}
+}
+
+// TestSerializerReadsOtherConfigurations 测试修改配置后仍能读取旧值
+func TestSerializerReadsOtherConfigurations(t *testing.T) {
+	writer, _ := newSerializer(CodecMsgpack, CompressionZstd, 1)
+	reader, _ := newSerializer(CodecJSON, CompressionNone, 0)
+
+	data, err := writer.encode(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	var got map[string]int
+	if err := reader.decode(data, &got); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if got["a"] != 1 {
+		t.Errorf("Expected 1, got %d", got["a"])
+	}
+
+	// 未带头部的旧数据按JSON解码
+	var legacy map[string]int
+	if err := reader.decode([]byte(`{"a":2}`), &legacy); err != nil {
+		t.Fatalf("Failed to decode legacy value: %v", err)
+	}
+	if legacy["a"] != 2 {
+		t.Errorf("Expected 2, got %d", legacy["a"])
+	}
+}
+
+// TestSerializerPlainJSON 测试未压缩的JSON值不带头部，与旧版本写入的格式一致
+func TestSerializerPlainJSON(t *testing.T) {
+	s, _ := newSerializer(CodecJSON, CompressionGzip, 1024)
+	data, err := s.encode(map[string]int{"a": 1})
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if string(data) != `{"a":1}` {
+		t.Errorf("Expected plain JSON, got %q", data)
+	}
+}
+
+// TestRedisCachePlainJSON 测试默认配置写入Redis的是原始JSON，数字值可以直接INCR
+func TestRedisCachePlainJSON(t *testing.T) {
+	server := miniredis.RunT(t)
+	c, err := NewRedisCache(&CacheConfig{Addr: server.Addr()})
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "user", codecPayload{Name: "a", Count: 1}, 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if raw, _ := server.Get("user"); raw != `{"Name":"a","Count":1,"Tags":null}` {
+		t.Errorf("Expected plain JSON in redis, got %q", raw)
+	}
+
+	if err := c.Set(ctx, "counter", 5, 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, err := c.Incr(ctx, "counter"); err != nil || value != 6 {
+		t.Errorf("Expected 6, got %d (err=%v)", value, err)
+	}
+	var got int
+	if err := c.Get(ctx, "counter", &got); err != nil || got != 6 {
+		t.Errorf("Expected 6, got %d (err=%v)", got, err)
+	}
+}
+
+// TestNewSerializerInvalidConfig 测试无效配置
+func TestNewSerializerInvalidConfig(t *testing.T) {
+	if _, err := newSerializer("xml", "", 0); err == nil {
+		t.Error("Expected error for unknown codec")
+	}
+	if _, err := newSerializer(CodecJSON, "lz4", 0); err == nil {
+		t.Error("Expected error for unknown compression")
+	}
+}
@@ -0,0 +1,462 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedisAddr 获取测试用Redis地址，可通过 VGO_TEST_REDIS_ADDR 覆盖
+func testRedisAddr() string {
+	if addr := os.Getenv("VGO_TEST_REDIS_ADDR"); addr != "" {
+		return addr
+	}
+	return "localhost:6379"
+}
+
+// newTestRedisCache 连接本地Redis，不可用时跳过测试
+func newTestRedisCache(t *testing.T) *RedisCache {
+	t.Helper()
+	c, err := NewRedisCache(&CacheConfig{Addr: testRedisAddr(), DialTimeout: 500 * time.Millisecond})
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", testRedisAddr(), err)
+	}
+	return c
+}
+
+// TestInMemoryCacheConformance 对内存缓存运行一致性测试
+func TestInMemoryCacheConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Cache {
+		c := NewInMemoryCache()
+		t.Cleanup(func() { c.Close() })
+		return c
+	})
+}
+
+// TestInMemoryCachePipelineUnsupported 测试内存缓存管道对不支持的命令返回明确错误
+func TestInMemoryCachePipelineUnsupported(t *testing.T) {
+	c := NewInMemoryCache()
+	defer c.Close()
+	ctx := context.Background()
+
+	pipe := c.Pipeline()
+	set := pipe.Set(ctx, "k", "v", 0)
+	eval := pipe.Eval(ctx, "return 1", nil)
+	if _, err := pipe.Exec(ctx); err == nil || eval.Err() == nil {
+		t.Errorf("Expected unsupported command error, got %v", err)
+	}
+	if set.Err() != nil {
+		t.Errorf("Expected supported command to run, got %v", set.Err())
+	}
+}
+
+// TestRedisCacheConformance 对Redis缓存运行一致性测试
+func TestRedisCacheConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Cache {
+		c := newTestRedisCache(t)
+		t.Cleanup(func() { c.Close() })
+		return c
+	})
+}
+
+// TestTieredCacheConformance 对二级缓存运行一致性测试
+func TestTieredCacheConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Cache {
+		c, err := NewTieredCache(newTestRedisCache(t), nil, nil)
+		if err != nil {
+			t.Fatalf("NewTieredCache failed: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	})
+}
+
+// runConformance 运行Cache接口的一致性测试套件
+// 每个子测试使用独立的键前缀，避免与Redis中已有数据冲突
+func runConformance(t *testing.T, newCache func(t *testing.T) Cache) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, c Cache, key func(string) string)
+	}{
+		{"Values", testConformanceValues},
+		{"Batch", testConformanceBatch},
+		{"Tags", testConformanceTags},
+		{"Expiration", testConformanceExpiration},
+		{"Counters", testConformanceCounters},
+		{"Hashes", testConformanceHashes},
+		{"Lists", testConformanceLists},
+		{"Sets", testConformanceSets},
+		{"SortedSets", testConformanceSortedSets},
+		{"WrongType", testConformanceWrongType},
+		{"Pipeline", testConformancePipeline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(t)
+			ctx := context.Background()
+			prefix := "conformance:" + uuid.NewString() + ":"
+			var used []string
+			key := func(name string) string {
+				used = append(used, prefix+name)
+				return prefix + name
+			}
+			t.Cleanup(func() {
+				if len(used) > 0 {
+					_ = c.Del(context.Background(), used...)
+				}
+			})
+			tt.fn(t, ctx, c, key)
+		})
+	}
+}
+
+// conformanceUser 一致性测试使用的结构体
+type conformanceUser struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func testConformanceValues(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	var user conformanceUser
+	if err := c.Get(ctx, key("missing"), &user); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing key, got %v", err)
+	}
+	if _, err := c.GetString(ctx, key("missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing string, got %v", err)
+	}
+
+	want := conformanceUser{ID: 42, Name: "alice"}
+	if err := c.Set(ctx, key("user"), want, 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Get(ctx, key("user"), &user); err != nil || user != want {
+		t.Errorf("Expected %+v, got %+v (err=%v)", want, user, err)
+	}
+
+	if err := c.SetString(ctx, key("str"), "hello", 0); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	if value, err := c.GetString(ctx, key("str")); err != nil || value != "hello" {
+		t.Errorf("Expected 'hello', got %q (err=%v)", value, err)
+	}
+
+	if n, err := c.Exists(ctx, key("user"), key("str"), key("missing")); err != nil || n != 2 {
+		t.Errorf("Expected 2 existing keys, got %d (err=%v)", n, err)
+	}
+
+	if err := c.Del(ctx, key("user"), key("str")); err != nil {
+		t.Fatalf("Del failed: %v", err)
+	}
+	if n, _ := c.Exists(ctx, key("user"), key("str")); n != 0 {
+		t.Errorf("Expected keys to be deleted, %d still exist", n)
+	}
+}
+
+func testConformanceBatch(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	values := map[string]interface{}{
+		key("a"): conformanceUser{ID: 1, Name: "a"},
+		key("b"): conformanceUser{ID: 2, Name: "b"},
+	}
+	if err := c.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatalf("MSet failed: %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, key("a")); ttl <= 0 {
+		t.Errorf("Expected MSet to apply expiration, got TTL %v", ttl)
+	}
+
+	var users map[string]conformanceUser
+	if err := c.MGet(ctx, []string{key("a"), key("b"), key("missing")}, &users); err != nil {
+		t.Fatalf("MGet failed: %v", err)
+	}
+	if len(users) != 2 || users[key("a")].ID != 1 || users[key("b")].Name != "b" {
+		t.Errorf("Unexpected MGet result %+v", users)
+	}
+
+	var invalid []conformanceUser
+	if err := c.MGet(ctx, []string{key("a")}, &invalid); err == nil {
+		t.Error("Expected error for non-map dest")
+	}
+
+	if err := c.MDel(ctx, key("a"), key("b")); err != nil {
+		t.Fatalf("MDel failed: %v", err)
+	}
+	if n, _ := c.Exists(ctx, key("a"), key("b")); n != 0 {
+		t.Errorf("Expected keys to be deleted, %d still exist", n)
+	}
+}
+
+func testConformanceTags(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	user, users := key("tag:user"), key("tag:users")
+
+	if err := c.Set(ctx, key("profile"), "p", time.Minute, WithTags(user)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, key("list"), "l", 0, WithTags(user, users)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, key("other"), "o", time.Minute, WithTags(users)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, key("profile")); ttl <= 0 {
+		t.Errorf("Expected tagged Set to apply expiration, got TTL %v", ttl)
+	}
+
+	if err := c.InvalidateTags(ctx, user); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+	if n, _ := c.Exists(ctx, key("profile"), key("list")); n != 0 {
+		t.Errorf("Expected tagged keys to be deleted, %d still exist", n)
+	}
+	var value string
+	if err := c.Get(ctx, key("other"), &value); err != nil || value != "o" {
+		t.Errorf("Expected untouched key 'o', got %q (err=%v)", value, err)
+	}
+
+	if err := c.InvalidateTags(ctx, users, key("tag:missing")); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+	if n, _ := c.Exists(ctx, key("other")); n != 0 {
+		t.Error("Expected key to be deleted")
+	}
+}
+
+func testConformanceExpiration(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	if ttl, err := c.TTL(ctx, key("missing")); err != nil || ttl != -2 {
+		t.Errorf("Expected TTL -2 for missing key, got %v (err=%v)", ttl, err)
+	}
+
+	_ = c.SetString(ctx, key("persistent"), "v", 0)
+	if ttl, err := c.TTL(ctx, key("persistent")); err != nil || ttl != -1 {
+		t.Errorf("Expected TTL -1 for key without expiration, got %v (err=%v)", ttl, err)
+	}
+
+	_ = c.SetString(ctx, key("volatile"), "v", 10*time.Second)
+	if ttl, err := c.TTL(ctx, key("volatile")); err != nil || ttl < 9*time.Second || ttl > 10*time.Second {
+		t.Errorf("Expected TTL around 10s, got %v (err=%v)", ttl, err)
+	}
+
+	if err := c.Expire(ctx, key("persistent"), 20*time.Second); err != nil {
+		t.Fatalf("Expire failed: %v", err)
+	}
+	if ttl, _ := c.TTL(ctx, key("persistent")); ttl < 19*time.Second || ttl > 20*time.Second {
+		t.Errorf("Expected TTL around 20s after Expire, got %v", ttl)
+	}
+
+	_ = c.SetString(ctx, key("short"), "v", 100*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	if _, err := c.GetString(ctx, key("short")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected expired key to be gone, got %v", err)
+	}
+}
+
+func testConformanceCounters(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	if n, err := c.Incr(ctx, key("counter")); err != nil || n != 1 {
+		t.Errorf("Expected Incr on missing key to return 1, got %d (err=%v)", n, err)
+	}
+	_, _ = c.Incr(ctx, key("counter"))
+	if n, err := c.Decr(ctx, key("counter")); err != nil || n != 1 {
+		t.Errorf("Expected counter to be 1, got %d (err=%v)", n, err)
+	}
+	if n, err := c.Decr(ctx, key("negative")); err != nil || n != -1 {
+		t.Errorf("Expected Decr on missing key to return -1, got %d (err=%v)", n, err)
+	}
+
+	_ = c.SetString(ctx, key("text"), "abc", 0)
+	if _, err := c.Incr(ctx, key("text")); err == nil {
+		t.Error("Expected error when incrementing non-integer value")
+	}
+}
+
+func testConformanceHashes(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	if _, err := c.HGet(ctx, key("hash"), "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing hash, got %v", err)
+	}
+	if all, err := c.HGetAll(ctx, key("hash")); err != nil || len(all) != 0 {
+		t.Errorf("Expected empty map for missing hash, got %v (err=%v)", all, err)
+	}
+
+	if err := c.HSet(ctx, key("hash"), "a", "1", "b", 2); err != nil {
+		t.Fatalf("HSet failed: %v", err)
+	}
+	if err := c.HSet(ctx, key("hash"), map[string]interface{}{"c": "3"}); err != nil {
+		t.Fatalf("HSet with map failed: %v", err)
+	}
+	if value, err := c.HGet(ctx, key("hash"), "b"); err != nil || value != "2" {
+		t.Errorf("Expected '2', got %q (err=%v)", value, err)
+	}
+	if _, err := c.HGet(ctx, key("hash"), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing field, got %v", err)
+	}
+
+	want := map[string]string{"a": "1", "b": "2", "c": "3"}
+	if all, err := c.HGetAll(ctx, key("hash")); err != nil || !reflect.DeepEqual(all, want) {
+		t.Errorf("Expected %v, got %v (err=%v)", want, all, err)
+	}
+
+	_ = c.HDel(ctx, key("hash"), "a", "b", "c")
+	if n, _ := c.Exists(ctx, key("hash")); n != 0 {
+		t.Error("Expected hash to be removed after deleting all fields")
+	}
+}
+
+func testConformanceLists(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	if _, err := c.LPop(ctx, key("list")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound popping missing list, got %v", err)
+	}
+
+	_ = c.RPush(ctx, key("list"), "b", "c")
+	_ = c.LPush(ctx, key("list"), "a", 0)
+	if n, err := c.LLen(ctx, key("list")); err != nil || n != 4 {
+		t.Errorf("Expected list length 4, got %d (err=%v)", n, err)
+	}
+
+	var got []string
+	for i := 0; i < 2; i++ {
+		value, err := c.LPop(ctx, key("list"))
+		if err != nil {
+			t.Fatalf("LPop failed: %v", err)
+		}
+		got = append(got, value)
+	}
+	value, _ := c.RPop(ctx, key("list"))
+	got = append(got, value)
+	if want := []string{"0", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected pop order %v, got %v", want, got)
+	}
+
+	_, _ = c.RPop(ctx, key("list"))
+	if n, _ := c.Exists(ctx, key("list")); n != 0 {
+		t.Error("Expected list to be removed after popping all elements")
+	}
+}
+
+func testConformanceSets(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	_ = c.SAdd(ctx, key("set"), "a", "b", "a", 1)
+	members, err := c.SMembers(ctx, key("set"))
+	if err != nil {
+		t.Fatalf("SMembers failed: %v", err)
+	}
+	sort.Strings(members)
+	if want := []string{"1", "a", "b"}; !reflect.DeepEqual(members, want) {
+		t.Errorf("Expected members %v, got %v", want, members)
+	}
+
+	if ok, _ := c.SIsMember(ctx, key("set"), 1); !ok {
+		t.Error("Expected 1 to be a member")
+	}
+	_ = c.SRem(ctx, key("set"), "a")
+	if ok, _ := c.SIsMember(ctx, key("set"), "a"); ok {
+		t.Error("Expected 'a' to be removed")
+	}
+	if members, _ := c.SMembers(ctx, key("missing")); len(members) != 0 {
+		t.Errorf("Expected no members for missing set, got %v", members)
+	}
+}
+
+func testConformanceSortedSets(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	_ = c.ZAdd(ctx, key("zset"),
+		redis.Z{Score: 3, Member: "c"},
+		redis.Z{Score: 1, Member: "a"},
+		redis.Z{Score: 2, Member: "b"},
+		redis.Z{Score: 2, Member: "bb"},
+	)
+
+	if members, err := c.ZRange(ctx, key("zset"), 0, -1); err != nil || !reflect.DeepEqual(members, []string{"a", "b", "bb", "c"}) {
+		t.Errorf("Expected ordered members, got %v (err=%v)", members, err)
+	}
+	if members, _ := c.ZRange(ctx, key("zset"), -2, -1); !reflect.DeepEqual(members, []string{"bb", "c"}) {
+		t.Errorf("Expected last two members, got %v", members)
+	}
+	if members, _ := c.ZRange(ctx, key("zset"), 5, 10); len(members) != 0 {
+		t.Errorf("Expected empty range, got %v", members)
+	}
+
+	_ = c.ZAdd(ctx, key("zset"), redis.Z{Score: 0, Member: "c"})
+	_ = c.ZRem(ctx, key("zset"), "b")
+	want := []redis.Z{{Score: 0, Member: "c"}, {Score: 1, Member: "a"}, {Score: 2, Member: "bb"}}
+	if members, err := c.ZRangeWithScores(ctx, key("zset"), 0, -1); err != nil || !reflect.DeepEqual(members, want) {
+		t.Errorf("Expected %v, got %v (err=%v)", want, members, err)
+	}
+}
+
+func testConformanceWrongType(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	_ = c.SetString(ctx, key("str"), "v", 0)
+	if err := c.LPush(ctx, key("str"), "x"); err == nil {
+		t.Error("Expected WRONGTYPE error pushing to string key")
+	}
+	if _, err := c.HGet(ctx, key("str"), "f"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected WRONGTYPE error reading hash field of string key, got %v", err)
+	}
+
+	_ = c.SAdd(ctx, key("set"), "a")
+	if _, err := c.GetString(ctx, key("set")); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected WRONGTYPE error reading set as string, got %v", err)
+	}
+}
+
+func testConformancePipeline(t *testing.T, ctx context.Context, c Cache, key func(string) string) {
+	pipe := c.Pipeline()
+	set := pipe.Set(ctx, key("str"), "v", time.Minute)
+	incr := pipe.IncrBy(ctx, key("counter"), 5)
+	hset := pipe.HSet(ctx, key("hash"), "f1", "v1", "f2", "v2")
+	push := pipe.RPush(ctx, key("list"), "a", "b")
+	zadd := pipe.ZAdd(ctx, key("zset"), redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 1, Member: "a"})
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Pipeline Exec failed: %v", err)
+	}
+	if set.Val() != "OK" || incr.Val() != 5 || hset.Val() != 2 || push.Val() != 2 || zadd.Val() != 2 {
+		t.Errorf("Unexpected pipeline results: set=%q incr=%d hset=%d push=%d zadd=%d",
+			set.Val(), incr.Val(), hset.Val(), push.Val(), zadd.Val())
+	}
+
+	pipe = c.Pipeline()
+	get := pipe.Get(ctx, key("str"))
+	missing := pipe.Get(ctx, key("missing"))
+	mget := pipe.MGet(ctx, key("str"), key("missing"))
+	ttl := pipe.TTL(ctx, key("str"))
+	hgetAll := pipe.HGetAll(ctx, key("hash"))
+	zrange := pipe.ZRangeWithScores(ctx, key("zset"), 0, -1)
+	wrongType := pipe.Incr(ctx, key("hash"))
+	if _, err := pipe.Exec(ctx); !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected Exec to return the first error redis.Nil, got %v", err)
+	}
+	if get.Val() != "v" || !errors.Is(missing.Err(), redis.Nil) {
+		t.Errorf("Unexpected GET results %q, %v", get.Val(), missing.Err())
+	}
+	if values := mget.Val(); len(values) != 2 || values[0] != "v" || values[1] != nil {
+		t.Errorf("Unexpected MGET result %v", values)
+	}
+	if ttl.Val() <= 0 || ttl.Val() > time.Minute {
+		t.Errorf("Expected TTL within a minute, got %v", ttl.Val())
+	}
+	if !reflect.DeepEqual(hgetAll.Val(), map[string]string{"f1": "v1", "f2": "v2"}) {
+		t.Errorf("Unexpected HGETALL result %v", hgetAll.Val())
+	}
+	if z := zrange.Val(); len(z) != 2 || z[0].Member != "a" || z[1].Score != 2 {
+		t.Errorf("Unexpected ZRANGE result %v", z)
+	}
+	if wrongType.Err() == nil {
+		t.Error("Expected WRONGTYPE error for INCR on hash")
+	}
+
+	tx := c.TxPipeline()
+	incr = tx.Incr(ctx, key("counter"))
+	del := tx.Del(ctx, key("str"), key("missing"))
+	if _, err := tx.Exec(ctx); err != nil {
+		t.Fatalf("TxPipeline Exec failed: %v", err)
+	}
+	if incr.Val() != 6 || del.Val() != 1 {
+		t.Errorf("Unexpected tx results: incr=%d del=%d", incr.Val(), del.Val())
+	}
+	if n, _ := c.Exists(ctx, key("str")); n != 0 {
+		t.Error("Expected key deleted by tx pipeline")
+	}
+}
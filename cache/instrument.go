@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+)
+
+// Option 缓存创建选项
+type Option func(*options)
+
+// options 缓存创建选项
+type options struct {
+	metrics metrics.MetricsCollector
+	logger  *zap.Logger
+}
+
+// WithMetrics 记录命令耗时、命中率和连接池指标，collector需实现 metrics.CacheMetrics，否则不记录
+func WithMetrics(collector metrics.MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = collector
+	}
+}
+
+// WithLogger 设置慢命令日志使用的日志器
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// cacheMetrics 返回collector实现的缓存指标接口，collector为nil或未实现时返回nil
+func cacheMetrics(collector metrics.MetricsCollector) metrics.CacheMetrics {
+	m, _ := collector.(metrics.CacheMetrics)
+	return m
+}
+
+// applyOptions 应用选项
+func applyOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	return o
+}
+
+// accessRecordedKey 上下文中标记读命令的命中情况已由调用方(如TieredCache)记录，钩子不再重复记录
+type accessRecordedKey struct{}
+
+// withAccessRecorded 标记上下文中读命令的命中情况由调用方记录
+func withAccessRecorded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, accessRecordedKey{}, true)
+}
+
+// accessRecorded 判断读命令的命中情况是否已由调用方记录
+func accessRecorded(ctx context.Context) bool {
+	recorded, _ := ctx.Value(accessRecordedKey{}).(bool)
+	return recorded
+}
+
+// hitMissCommands 记录命中率的读命令，MGET按键分别记录
+var hitMissCommands = map[string]bool{
+	"get":  true,
+	"hget": true,
+	"mget": true,
+}
+
+// instrumentationHook go-redis钩子，记录命令指标、慢命令日志和Sentry链路
+type instrumentationHook struct {
+	metrics       metrics.CacheMetrics
+	logger        *zap.Logger
+	slowThreshold time.Duration
+	tracing       bool
+}
+
+// DialHook 不处理建连
+func (h *instrumentationHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 记录单个命令
+func (h *instrumentationHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		span := h.startSpan(ctx, cmd.Name())
+		start := time.Now()
+		err := next(ctx, cmd)
+		duration := time.Since(start)
+
+		h.observe(ctx, cmd, duration, err)
+		h.logSlow(cmd.Name(), cmd, duration, 1)
+		h.finishSpan(span, err)
+		return err
+	}
+}
+
+// ProcessPipelineHook 以pipeline为单位记录耗时，命中率按命令记录
+func (h *instrumentationHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		span := h.startSpan(ctx, "pipeline")
+		start := time.Now()
+		err := next(ctx, cmds)
+		duration := time.Since(start)
+
+		if h.metrics != nil {
+			h.metrics.RecordCacheCommand("pipeline", duration, commandError(err))
+			if !accessRecorded(ctx) {
+				for _, cmd := range cmds {
+					h.recordAccess(cmd, cmd.Err())
+				}
+			}
+		}
+		var first redis.Cmder
+		if len(cmds) > 0 {
+			first = cmds[0]
+		}
+		h.logSlow("pipeline", first, duration, len(cmds))
+		h.finishSpan(span, err)
+		return err
+	}
+}
+
+// observe 记录命令耗时和命中情况，钩子内命令的错误尚未写入cmd，需使用next返回的错误
+func (h *instrumentationHook) observe(ctx context.Context, cmd redis.Cmder, duration time.Duration, err error) {
+	if h.metrics == nil {
+		return
+	}
+	h.metrics.RecordCacheCommand(cmd.Name(), duration, commandError(err))
+	if !accessRecorded(ctx) {
+		h.recordAccess(cmd, err)
+	}
+}
+
+// recordAccess 记录读命令的命中情况
+func (h *instrumentationHook) recordAccess(cmd redis.Cmder, err error) {
+	if !hitMissCommands[cmd.Name()] {
+		return
+	}
+	if slice, ok := cmd.(*redis.SliceCmd); ok {
+		for _, value := range slice.Val() {
+			h.metrics.RecordCacheAccess(TierRedis, value != nil)
+		}
+		return
+	}
+	switch {
+	case err == nil:
+		h.metrics.RecordCacheAccess(TierRedis, true)
+	case errors.Is(err, redis.Nil):
+		h.metrics.RecordCacheAccess(TierRedis, false)
+	}
+}
+
+// logSlow 记录慢命令，只输出命令名和第一个键，避免泄露缓存值
+func (h *instrumentationHook) logSlow(name string, cmd redis.Cmder, duration time.Duration, count int) {
+	if h.slowThreshold <= 0 || duration < h.slowThreshold {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("command", name),
+		zap.Duration("duration", duration),
+	}
+	if count > 1 {
+		fields = append(fields, zap.Int("commands", count))
+	}
+	if cmd != nil && len(cmd.Args()) > 1 {
+		if key, ok := cmd.Args()[1].(string); ok {
+			fields = append(fields, zap.String("key", key))
+		}
+	}
+	h.logger.Warn("Slow redis command", fields...)
+}
+
+// startSpan 当前上下文存在Sentry事务时创建子span
+func (h *instrumentationHook) startSpan(ctx context.Context, name string) *sentry.Span {
+	if !h.tracing || sentry.TransactionFromContext(ctx) == nil {
+		return nil
+	}
+	span := sentry.StartSpan(ctx, "db.redis", sentry.WithDescription(name))
+	span.SetData("db.system", "redis")
+	return span
+}
+
+// finishSpan 结束span
+func (h *instrumentationHook) finishSpan(span *sentry.Span, err error) {
+	if span == nil {
+		return
+	}
+	if commandError(err) != nil {
+		span.Status = sentry.SpanStatusInternalError
+	} else {
+		span.Status = sentry.SpanStatusOK
+	}
+	span.Finish()
+}
+
+// commandError 未命中(redis.Nil)不视为错误
+func commandError(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// reportPoolStats 定期上报连接池指标，直到stop关闭
+func (r *RedisCache) reportPoolStats(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			stats := r.client.PoolStats()
+			r.metrics.UpdateCachePoolStats(r.config.Name, int(stats.Hits), int(stats.Misses), int(stats.Timeouts),
+				int(stats.IdleConns), int(stats.TotalConns))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recordingMetrics 记录缓存指标调用的收集器
+type recordingMetrics struct {
+	metrics.MetricsCollector
+	metrics.CacheMetrics
+	mu       sync.Mutex
+	hits     int
+	misses   int
+	commands map[string]int
+}
+
+func (m *recordingMetrics) RecordCacheAccess(tier string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+func (m *recordingMetrics) RecordCacheCommand(command string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands[command]++
+}
+
+func (m *recordingMetrics) UpdateCachePoolStats(pool string, hits, misses, timeouts, idle, total int) {
+}
+
+// TestRedisCacheInstrumentation 测试命令指标、命中率和慢命令日志
+func TestRedisCacheInstrumentation(t *testing.T) {
+	collector := &recordingMetrics{commands: make(map[string]int)}
+	core, logs := observer.New(zap.WarnLevel)
+
+	c, err := NewRedisCache(&CacheConfig{
+		Addr:          testRedisAddr(),
+		DialTimeout:   500 * time.Millisecond,
+		SlowThreshold: time.Nanosecond,
+	}, WithMetrics(collector), WithLogger(zap.New(core)))
+	if err != nil {
+		t.Skipf("redis not available at %s: %v", testRedisAddr(), err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	key := "instrument:" + t.Name()
+	defer c.Del(ctx, key)
+
+	if err := c.SetString(ctx, key, "v", time.Minute); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	c.GetString(ctx, key)
+	c.GetString(ctx, key+":missing")
+	var values map[string]string
+	c.MGet(ctx, []string{key, key + ":missing"}, &values)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if collector.hits != 2 || collector.misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %d and %d", collector.hits, collector.misses)
+	}
+	if collector.commands["get"] != 2 || collector.commands["set"] != 1 {
+		t.Errorf("Unexpected command counts %v", collector.commands)
+	}
+
+	slow := logs.FilterMessage("Slow redis command").FilterField(zap.String("key", key))
+	if slow.Len() == 0 {
+		t.Error("Expected slow command to be logged with its key")
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+)
+
+// keySeparator 缓存键分隔符
+const keySeparator = ":"
+
+// KeyBuilder 缓存键构建器
+// 生成形如 "namespace:sub:v2:part1:part2" 的键，提升版本号即可让旧格式的缓存整体失效
+type KeyBuilder struct {
+	namespaces []string
+	version    int
+}
+
+// NewKeyBuilder 创建缓存键构建器，namespace为空时不添加前缀
+func NewKeyBuilder(namespace string) *KeyBuilder {
+	return (&KeyBuilder{}).Namespace(namespace)
+}
+
+// Namespace 创建带子命名空间的新构建器
+func (kb *KeyBuilder) Namespace(namespace string) *KeyBuilder {
+	namespaces := make([]string, 0, len(kb.namespaces)+1)
+	namespaces = append(namespaces, kb.namespaces...)
+	if namespace != "" {
+		namespaces = append(namespaces, namespace)
+	}
+	return &KeyBuilder{namespaces: namespaces, version: kb.version}
+}
+
+// Version 创建带版本前缀的新构建器，version小于等于0时不添加版本前缀
+func (kb *KeyBuilder) Version(version int) *KeyBuilder {
+	return &KeyBuilder{namespaces: kb.namespaces, version: version}
+}
+
+// Prefix 获取键前缀（不含结尾分隔符）
+func (kb *KeyBuilder) Prefix() string {
+	parts := make([]string, 0, len(kb.namespaces)+1)
+	parts = append(parts, kb.namespaces...)
+	if kb.version > 0 {
+		parts = append(parts, "v"+strconv.Itoa(kb.version))
+	}
+	return strings.Join(parts, keySeparator)
+}
+
+// Key 构建完整的缓存键
+func (kb *KeyBuilder) Key(parts ...string) string {
+	prefix := kb.Prefix()
+	key := strings.Join(parts, keySeparator)
+	switch {
+	case prefix == "":
+		return key
+	case key == "":
+		return prefix
+	default:
+		return prefix + keySeparator + key
+	}
+}
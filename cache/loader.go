@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// releaseLockScript 仅当锁的持有者匹配时才删除锁
+var releaseLockScript = RegisterScript("cache:loader:release", `
+	if redis.call('GET', KEYS[1]) == ARGV[1] then
+		return redis.call('DEL', KEYS[1])
+	end
+	return 0
+`)
+
+// LoadFunc 数据加载函数，数据不存在时应返回 ErrNotFound 以便进行负缓存
+type LoadFunc[T any] func(ctx context.Context) (T, error)
+
+// LoaderConfig 缓存加载器配置
+type LoaderConfig struct {
+	DefaultTTL     time.Duration `mapstructure:"default_ttl"`     // 调用方传入的TTL小于等于0时使用的缓存时间
+	NegativeTTL    time.Duration `mapstructure:"negative_ttl"`    // 不存在结果的缓存时间，为0时不缓存
+	Jitter         float64       `mapstructure:"jitter"`          // TTL随机抖动比例(0-1)，避免大量键同时过期
+	StaleTTL       time.Duration `mapstructure:"stale_ttl"`       // 过期后仍可返回旧值的时长，为0时关闭
+	RefreshTimeout time.Duration `mapstructure:"refresh_timeout"` // 后台刷新超时时间
+	LockTTL        time.Duration `mapstructure:"lock_ttl"`        // 跨实例加载锁的过期时间，为0时关闭
+	LockWait       time.Duration `mapstructure:"lock_wait"`       // 未获取到锁时等待其他实例写入缓存的最长时间
+}
+
+// DefaultLoaderConfig 默认缓存加载器配置
+func DefaultLoaderConfig() *LoaderConfig {
+	return &LoaderConfig{
+		DefaultTTL:     5 * time.Minute,
+		NegativeTTL:    30 * time.Second,
+		Jitter:         0.1,
+		RefreshTimeout: 10 * time.Second,
+		LockWait:       time.Second,
+	}
+}
+
+// loaderEntry 缓存中保存的加载结果
+type loaderEntry[T any] struct {
+	Value      T     `json:"v"`
+	NotFound   bool  `json:"nf,omitempty"`
+	FreshUntil int64 `json:"fu"` // 毫秒时间戳，超过后视为过期值
+}
+
+// Loader 缓存旁路加载器
+// 同一进程内对同一键的并发未命中只会调用一次加载函数，可选通过Redis锁在实例间合并加载。
+// 缓存中保存的是封装了加载结果的结构体，因此不支持protobuf编解码器
+type Loader[T any] struct {
+	cache      Cache
+	config     LoaderConfig
+	group      singleflight.Group
+	refreshing sync.Map
+}
+
+// NewLoader 创建缓存旁路加载器，config为nil时使用默认配置
+func NewLoader[T any](cache Cache, config *LoaderConfig) *Loader[T] {
+	if config == nil {
+		config = DefaultLoaderConfig()
+	}
+	c := *config
+	if c.DefaultTTL <= 0 {
+		c.DefaultTTL = DefaultLoaderConfig().DefaultTTL
+	}
+	return &Loader[T]{
+		cache:  cache,
+		config: c,
+	}
+}
+
+// GetOrLoad 获取缓存值，未命中时调用load加载并写入缓存，ttl小于等于0时使用 DefaultTTL
+// 缓存值过期但仍在 StaleTTL 内时直接返回旧值，并在后台刷新
+func (l *Loader[T]) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T]) (T, error) {
+	if ttl <= 0 {
+		ttl = l.config.DefaultTTL
+	}
+	var entry loaderEntry[T]
+	err := l.cache.Get(ctx, key, &entry)
+	if err == nil {
+		if time.Now().UnixMilli() >= entry.FreshUntil {
+			l.refresh(ctx, key, ttl, load)
+		}
+		return entry.result()
+	}
+
+	// 缓存读取失败时降级为直接加载
+	ch := l.group.DoChan(key, func() (interface{}, error) {
+		return l.load(context.WithoutCancel(ctx), key, ttl, load)
+	})
+
+	var zero T
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		value, _ := res.Val.(T)
+		return value, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// refresh 在后台刷新过期值，同一键同时只有一个刷新任务
+func (l *Loader[T]) refresh(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T]) {
+	if _, running := l.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer l.refreshing.Delete(key)
+
+		refreshCtx := context.WithoutCancel(ctx)
+		if l.config.RefreshTimeout > 0 {
+			var cancel context.CancelFunc
+			refreshCtx, cancel = context.WithTimeout(refreshCtx, l.config.RefreshTimeout)
+			defer cancel()
+		}
+
+		_, _, _ = l.group.Do(key, func() (interface{}, error) {
+			return l.loadAndStore(refreshCtx, key, ttl, load)
+		})
+	}()
+}
+
+// load 加载数据，启用跨实例锁时只有持锁实例调用加载函数
+func (l *Loader[T]) load(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T]) (T, error) {
+	client := l.cache.GetClient()
+	if l.config.LockTTL <= 0 || client == nil {
+		return l.loadAndStore(ctx, key, ttl, load)
+	}
+
+	lockKey := key + ":lock"
+	token := uuid.NewString()
+	acquired, err := client.SetNX(ctx, lockKey, token, l.config.LockTTL).Result()
+	if err != nil {
+		return l.loadAndStore(ctx, key, ttl, load)
+	}
+	if acquired {
+		defer releaseLockScript.Run(context.WithoutCancel(ctx), client, []string{lockKey}, token)
+		return l.loadAndStore(ctx, key, ttl, load)
+	}
+
+	// 其他实例正在加载，等待其写入缓存
+	if value, found, err := l.waitForValue(ctx, key); found || err != nil {
+		return value, err
+	}
+	return l.loadAndStore(ctx, key, ttl, load)
+}
+
+// waitForValue 轮询等待其他实例写入缓存
+func (l *Loader[T]) waitForValue(ctx context.Context, key string) (T, bool, error) {
+	var zero T
+	deadline := time.Now().Add(l.config.LockWait)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return zero, false, ctx.Err()
+		case <-ticker.C:
+		}
+
+		var entry loaderEntry[T]
+		if err := l.cache.Get(ctx, key, &entry); err == nil {
+			value, err := entry.result()
+			return value, true, err
+		}
+	}
+	return zero, false, nil
+}
+
+// loadAndStore 调用加载函数并写入缓存
+func (l *Loader[T]) loadAndStore(ctx context.Context, key string, ttl time.Duration, load LoadFunc[T]) (T, error) {
+	value, err := load(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) && l.config.NegativeTTL > 0 {
+			l.store(ctx, key, loaderEntry[T]{NotFound: true}, l.config.NegativeTTL)
+		}
+		var zero T
+		return zero, err
+	}
+
+	l.store(ctx, key, loaderEntry[T]{Value: value}, ttl)
+	return value, nil
+}
+
+// store 写入缓存，写入失败不影响加载结果
+// 不存在的结果不保留过期值，NegativeTTL到期后直接删除
+func (l *Loader[T]) store(ctx context.Context, key string, entry loaderEntry[T], ttl time.Duration) {
+	ttl = l.jitter(ttl)
+	entry.FreshUntil = time.Now().Add(ttl).UnixMilli()
+	expiration := ttl
+	if !entry.NotFound {
+		expiration += l.config.StaleTTL
+	}
+	_ = l.cache.Set(ctx, key, entry, expiration)
+}
+
+// jitter 为TTL增加随机抖动
+func (l *Loader[T]) jitter(ttl time.Duration) time.Duration {
+	if l.config.Jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*l.config.Jitter*float64(ttl))
+}
+
+// result 将缓存条目转换为返回值
+func (e loaderEntry[T]) result() (T, error) {
+	if e.NotFound {
+		var zero T
+		return zero, ErrNotFound
+	}
+	return e.Value, nil
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mapCache 基于map的简易缓存，仅实现加载器用到的方法
+type mapCache struct {
+	NoOpCache
+	mu          sync.Mutex
+	data        map[string][]byte
+	expirations map[string]time.Duration
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{data: make(map[string][]byte), expirations: make(map[string]time.Duration)}
+}
+
+func (m *mapCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = data
+	m.expirations[key] = expiration
+	return nil
+}
+
+func (m *mapCache) Get(ctx context.Context, key string, dest interface{}) error {
+	m.mu.Lock()
+	data, ok := m.data[key]
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// TestLoaderCollapsesConcurrentMisses 测试并发未命中只加载一次
+func TestLoaderCollapsesConcurrentMisses(t *testing.T) {
+	loader := NewLoader[string](newMapCache(), &LoaderConfig{})
+
+	var calls int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := loader.GetOrLoad(context.Background(), "k", time.Minute, load)
+			if err != nil || value != "value" {
+				t.Errorf("Expected 'value', got %q (err=%v)", value, err)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected loader to be called once, got %d", calls)
+	}
+}
+
+// TestLoaderNegativeCaching 测试不存在结果的负缓存
+func TestLoaderNegativeCaching(t *testing.T) {
+	loader := NewLoader[int](newMapCache(), &LoaderConfig{NegativeTTL: time.Minute})
+
+	var calls int32
+	load := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, ErrNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := loader.GetOrLoad(context.Background(), "missing", time.Minute, load); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected loader to be called once, got %d", calls)
+	}
+}
+
+// TestLoaderStaleWhileRevalidate 测试过期值返回与后台刷新
+func TestLoaderStaleWhileRevalidate(t *testing.T) {
+	loader := NewLoader[int](newMapCache(), &LoaderConfig{StaleTTL: time.Minute})
+
+	var version int32
+	load := func(ctx context.Context) (int, error) {
+		return int(atomic.AddInt32(&version, 1)), nil
+	}
+
+	if value, _ := loader.GetOrLoad(context.Background(), "k", 10*time.Millisecond, load); value != 1 {
+		t.Fatalf("Expected initial value 1, got %d", value)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if value, _ := loader.GetOrLoad(context.Background(), "k", 10*time.Millisecond, load); value != 1 {
+		t.Errorf("Expected stale value 1, got %d", value)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if value, _ := loader.GetOrLoad(context.Background(), "k", time.Minute, load); value >= 2 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("Expected background refresh to store a new value")
+}
+
+// TestLoaderExpiration 测试TTL为0时使用默认TTL，不存在的结果不保留过期值
+func TestLoaderExpiration(t *testing.T) {
+	cache := newMapCache()
+	loader := NewLoader[int](cache, &LoaderConfig{DefaultTTL: time.Minute, NegativeTTL: time.Second, StaleTTL: time.Hour})
+	ctx := context.Background()
+
+	if _, err := loader.GetOrLoad(ctx, "k", 0, func(ctx context.Context) (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("GetOrLoad failed: %v", err)
+	}
+	if got := cache.expirations["k"]; got != time.Minute+time.Hour {
+		t.Errorf("Expected default ttl plus stale ttl, got %v", got)
+	}
+	var entry loaderEntry[int]
+	cache.Get(ctx, "k", &entry)
+	if entry.FreshUntil <= time.Now().UnixMilli() {
+		t.Error("Expected entry loaded with zero ttl to be fresh")
+	}
+
+	loader.GetOrLoad(ctx, "missing", time.Minute, func(ctx context.Context) (int, error) { return 0, ErrNotFound })
+	if got := cache.expirations["missing"]; got != time.Second {
+		t.Errorf("Expected negative entry to expire after NegativeTTL, got %v", got)
+	}
+}
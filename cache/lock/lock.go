@@ -0,0 +1,265 @@
+// Package lock 提供基于Redis的分布式锁，以及用于测试的内存实现
+package lock
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 锁相关错误
+var (
+	// ErrNotAcquired 锁已被其他持有者占用
+	ErrNotAcquired = errors.New("lock: not acquired")
+	// ErrNotHeld 锁已过期或被其他持有者获取
+	ErrNotHeld = errors.New("lock: not held")
+	// ErrInvalidTTL 锁的过期时间小于1毫秒，Redis按毫秒设置过期时间无法表示
+	ErrInvalidTTL = errors.New("lock: ttl must be at least 1ms")
+)
+
+// Config 分布式锁配置
+type Config struct {
+	Prefix     string        `mapstructure:"prefix"`      // 锁键前缀
+	MinBackoff time.Duration `mapstructure:"min_backoff"` // 重试最小退避时间
+	MaxBackoff time.Duration `mapstructure:"max_backoff"` // 重试最大退避时间
+	AutoExtend bool          `mapstructure:"auto_extend"` // 持有期间是否自动续期
+}
+
+// DefaultConfig 默认锁配置
+func DefaultConfig() *Config {
+	return &Config{
+		Prefix:     "vgo:lock:",
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: time.Second,
+		AutoExtend: true,
+	}
+}
+
+// Locker 分布式锁接口
+type Locker interface {
+	// TryAcquire 尝试获取一次锁，锁被占用时返回ErrNotAcquired，ttl小于1毫秒时返回ErrInvalidTTL
+	TryAcquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+	// Acquire 获取锁，锁被占用时按指数退避重试，直到成功或ctx结束
+	Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+}
+
+// backend 锁存储后端
+type backend interface {
+	// acquire 获取锁，成功时返回递增的fencing token
+	acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error)
+	// extend 持有者为owner时延长锁的过期时间
+	extend(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// release 持有者为owner时删除锁
+	release(ctx context.Context, key, owner string) (bool, error)
+}
+
+// locker Locker的通用实现，退避和续期逻辑与存储后端无关
+type locker struct {
+	backend backend
+	config  Config
+}
+
+// newLocker 创建锁实例并补全默认配置
+func newLocker(b backend, config *Config) *locker {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	c := *config
+	if c.Prefix == "" {
+		c.Prefix = "vgo:lock:"
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = 10 * time.Millisecond
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = c.MinBackoff
+	}
+	return &locker{backend: b, config: c}
+}
+
+// TryAcquire 尝试获取一次锁
+func (l *locker) TryAcquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	if ttl < time.Millisecond {
+		return nil, ErrInvalidTTL
+	}
+	// 使用hash tag保证锁键与token计数器在集群中位于同一slot
+	key := l.config.Prefix + "{" + name + "}"
+	owner := uuid.NewString()
+	token, ok, err := l.backend.acquire(ctx, key, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+
+	lock := &Lock{
+		name:    name,
+		key:     key,
+		owner:   owner,
+		token:   token,
+		ttl:     ttl,
+		backend: l.backend,
+		lost:    make(chan struct{}),
+	}
+	if l.config.AutoExtend {
+		lock.startRefresh()
+	}
+	return lock, nil
+}
+
+// Acquire 获取锁，锁被占用时按带抖动的指数退避重试
+func (l *locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	backoff := l.config.MinBackoff
+	for {
+		lock, err := l.TryAcquire(ctx, name, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lock, err
+		}
+
+		wait := backoff/2 + time.Duration(rand.Int64N(int64(backoff/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > l.config.MaxBackoff {
+			backoff = l.config.MaxBackoff
+		}
+	}
+}
+
+// Lock 已获取的锁
+type Lock struct {
+	name    string
+	key     string
+	owner   string
+	token   int64
+	ttl     time.Duration
+	backend backend
+
+	mu       sync.Mutex
+	released bool
+	lost     chan struct{}
+	lostOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Name 获取锁名称
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Token 获取fencing token，同一锁名称每次获取都会递增。
+// 写入受保护资源时携带该值，资源方拒绝比已见过的token更小的请求，避免过期持有者覆盖数据。
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Lost 返回在自动续期失败、锁已不再被持有时关闭的通道
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Extend 延长锁的过期时间
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	if ttl < time.Millisecond {
+		return ErrInvalidTTL
+	}
+	ok, err := l.backend.extend(ctx, l.key, l.owner, ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.markLost()
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release 释放锁，只有当前持有者才能删除锁，锁已过期时返回ErrNotHeld
+func (l *Lock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return ErrNotHeld
+	}
+	l.released = true
+	l.mu.Unlock()
+
+	l.stopRefresh()
+
+	ok, err := l.backend.release(ctx, l.key, l.owner)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.markLost()
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// markLost 标记锁已丢失
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// startRefresh 启动后台续期，每隔ttl/3续期一次
+func (l *Lock) startRefresh() {
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.refresh()
+}
+
+// stopRefresh 停止后台续期
+func (l *Lock) stopRefresh() {
+	if l.stop == nil {
+		return
+	}
+	close(l.stop)
+	<-l.done
+}
+
+// refresh 后台续期循环，续期出错时继续重试，直到锁过期
+func (l *Lock) refresh() {
+	defer close(l.done)
+
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(l.ttl)
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.Extend(ctx, l.ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			deadline = time.Now().Add(l.ttl)
+		case errors.Is(err, ErrNotHeld):
+			return
+		case time.Now().After(deadline):
+			l.markLost()
+			return
+		}
+	}
+}
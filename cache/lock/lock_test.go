@@ -0,0 +1,181 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisClient 连接本地Redis，不可用时跳过测试，可通过 VGO_TEST_REDIS_ADDR 覆盖地址
+func newTestRedisClient(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	addr := os.Getenv("VGO_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, DialTimeout: 500 * time.Millisecond})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestMemoryLocker 对内存锁运行测试
+func TestMemoryLocker(t *testing.T) {
+	runLockerTests(t, func(t *testing.T, config *Config) Locker {
+		return NewMemoryLocker(config)
+	})
+}
+
+// TestRedisLocker 对Redis锁运行测试
+func TestRedisLocker(t *testing.T) {
+	runLockerTests(t, func(t *testing.T, config *Config) Locker {
+		config.Prefix = "vgo:test:lock:" + uuid.NewString() + ":"
+		return NewRedisLocker(newTestRedisClient(t), config)
+	})
+}
+
+func runLockerTests(t *testing.T, newLocker func(*testing.T, *Config) Locker) {
+	ctx := context.Background()
+
+	t.Run("MutualExclusion", func(t *testing.T) {
+		locker := newLocker(t, &Config{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, AutoExtend: true})
+
+		var holders, maxHolders int32
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lock, err := locker.Acquire(ctx, "job", time.Second)
+				if err != nil {
+					t.Errorf("Failed to acquire lock: %v", err)
+					return
+				}
+				if n := atomic.AddInt32(&holders, 1); n > atomic.LoadInt32(&maxHolders) {
+					atomic.StoreInt32(&maxHolders, n)
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&holders, -1)
+				if err := lock.Release(ctx); err != nil {
+					t.Errorf("Failed to release lock: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if maxHolders != 1 {
+			t.Errorf("Expected at most 1 concurrent holder, got %d", maxHolders)
+		}
+	})
+
+	t.Run("FencingToken", func(t *testing.T) {
+		locker := newLocker(t, &Config{})
+
+		first, err := locker.TryAcquire(ctx, "token", time.Second)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		if _, err := locker.TryAcquire(ctx, "token", time.Second); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("Expected ErrNotAcquired, got %v", err)
+		}
+		first.Release(ctx)
+
+		second, err := locker.TryAcquire(ctx, "token", time.Second)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		defer second.Release(ctx)
+		if second.Token() <= first.Token() {
+			t.Errorf("Expected increasing token, got %d after %d", second.Token(), first.Token())
+		}
+	})
+
+	t.Run("InvalidTTL", func(t *testing.T) {
+		locker := newLocker(t, &Config{})
+
+		for _, ttl := range []time.Duration{0, 500 * time.Microsecond} {
+			if _, err := locker.TryAcquire(ctx, "invalid", ttl); !errors.Is(err, ErrInvalidTTL) {
+				t.Errorf("Expected ErrInvalidTTL for %v, got %v", ttl, err)
+			}
+		}
+		lock, err := locker.TryAcquire(ctx, "invalid", time.Second)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		defer lock.Release(ctx)
+		if err := lock.Extend(ctx, 500*time.Microsecond); !errors.Is(err, ErrInvalidTTL) {
+			t.Errorf("Expected ErrInvalidTTL from Extend, got %v", err)
+		}
+	})
+
+	t.Run("ExpiredLockCannotBeReleased", func(t *testing.T) {
+		locker := newLocker(t, &Config{})
+
+		stale, err := locker.TryAcquire(ctx, "expire", 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		current, err := locker.TryAcquire(ctx, "expire", time.Second)
+		if err != nil {
+			t.Fatalf("Expected lock to be acquirable after expiry, got %v", err)
+		}
+		defer current.Release(ctx)
+
+		if err := stale.Release(ctx); !errors.Is(err, ErrNotHeld) {
+			t.Errorf("Expected ErrNotHeld, got %v", err)
+		}
+		if _, err := locker.TryAcquire(ctx, "expire", time.Second); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("Expected current holder to keep the lock, got %v", err)
+		}
+	})
+
+	t.Run("AutoExtend", func(t *testing.T) {
+		locker := newLocker(t, &Config{AutoExtend: true})
+
+		lock, err := locker.TryAcquire(ctx, "extend", 150*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		time.Sleep(400 * time.Millisecond)
+
+		if _, err := locker.TryAcquire(ctx, "extend", time.Second); !errors.Is(err, ErrNotAcquired) {
+			t.Errorf("Expected lock to be extended, got %v", err)
+		}
+		select {
+		case <-lock.Lost():
+			t.Error("Expected lock not to be lost")
+		default:
+		}
+		if err := lock.Release(ctx); err != nil {
+			t.Errorf("Failed to release lock: %v", err)
+		}
+	})
+
+	t.Run("AcquireHonorsContext", func(t *testing.T) {
+		locker := newLocker(t, &Config{})
+
+		lock, err := locker.TryAcquire(ctx, "busy", time.Second)
+		if err != nil {
+			t.Fatalf("Failed to acquire lock: %v", err)
+		}
+		defer lock.Release(ctx)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := locker.Acquire(timeoutCtx, "busy", time.Second); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
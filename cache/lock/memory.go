@@ -0,0 +1,75 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryEntry 内存锁条目
+type memoryEntry struct {
+	owner     string
+	expiresAt time.Time
+}
+
+// memoryBackend 进程内锁后端，用于测试或单实例部署
+type memoryBackend struct {
+	mu     sync.Mutex
+	locks  map[string]memoryEntry
+	tokens map[string]int64
+}
+
+// NewMemoryLocker 创建内存锁，config为nil时使用默认配置
+func NewMemoryLocker(config *Config) Locker {
+	return newLocker(&memoryBackend{
+		locks:  make(map[string]memoryEntry),
+		tokens: make(map[string]int64),
+	}, config)
+}
+
+// holder 获取未过期的持有者，调用方需持有锁
+func (b *memoryBackend) holder(key string) (memoryEntry, bool) {
+	entry, ok := b.locks[key]
+	if ok && !time.Now().Before(entry.expiresAt) {
+		delete(b.locks, key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+func (b *memoryBackend) acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, held := b.holder(key); held {
+		return 0, false, nil
+	}
+	b.locks[key] = memoryEntry{owner: owner, expiresAt: time.Now().Add(ttl)}
+	b.tokens[key]++
+	return b.tokens[key], true, nil
+}
+
+func (b *memoryBackend) extend(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, held := b.holder(key)
+	if !held || entry.owner != owner {
+		return false, nil
+	}
+	entry.expiresAt = time.Now().Add(ttl)
+	b.locks[key] = entry
+	return true, nil
+}
+
+func (b *memoryBackend) release(ctx context.Context, key, owner string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, held := b.holder(key)
+	if !held || entry.owner != owner {
+		return false, nil
+	}
+	delete(b.locks, key)
+	return true, nil
+}
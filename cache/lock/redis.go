@@ -0,0 +1,68 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/cache"
+)
+
+// acquireScript 获取锁并递增fencing token
+// KEYS[1]: 锁键, KEYS[2]: token计数器; ARGV[1]: 持有者标识, ARGV[2]: 过期时间(毫秒)
+var acquireScript = cache.RegisterScript("lock:acquire", `
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return redis.call('INCR', KEYS[2])
+end
+return 0
+`)
+
+// extendScript 持有者匹配时延长过期时间
+var extendScript = cache.RegisterScript("lock:extend", `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 持有者匹配时删除锁
+var releaseScript = cache.RegisterScript("lock:release", `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// redisBackend Redis锁后端
+type redisBackend struct {
+	client redis.UniversalClient
+}
+
+// NewRedisLocker 创建Redis分布式锁，config为nil时使用默认配置
+func NewRedisLocker(client redis.UniversalClient, config *Config) Locker {
+	return newLocker(&redisBackend{client: client}, config)
+}
+
+func (b *redisBackend) acquire(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	token, err := acquireScript.Run(ctx, b.client, []string{key, key + ":token"}, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	return token, token > 0, nil
+}
+
+func (b *redisBackend) extend(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	result, err := extendScript.Run(ctx, b.client, []string{key}, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
+
+func (b *redisBackend) release(ctx context.Context, key, owner string) (bool, error) {
+	result, err := releaseScript.Run(ctx, b.client, []string{key}, owner).Int64()
+	if err != nil {
+		return false, err
+	}
+	return result == 1, nil
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// generationStripes 失效代数的分段数，同一分段内的键共享代数
+const generationStripes = 256
+
+// lruCache 容量受限的进程内LRU缓存，保存编码后的原始值
+// 删除键时递增其所在分段的代数，读取远端前记录代数，回填时代数变化说明期间发生过失效，放弃回填
+type lruCache struct {
+	mu          sync.Mutex
+	capacity    int
+	items       map[string]*list.Element
+	order       *list.List
+	generations [generationStripes]uint64
+}
+
+// lruEntry LRU缓存条目
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// newLRUCache 创建LRU缓存，capacity小于等于0时默认10000
+func newLRUCache(capacity int) *lruCache {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &lruCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get 获取未过期的条目，并将其移动到队首
+func (c *lruCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set 写入条目，超出容量时淘汰最久未使用的条目
+func (c *lruCache) set(key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(key, value, ttl)
+}
+
+// generation 获取键当前的失效代数
+func (c *lruCache) generation(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[generationStripe(key)]
+}
+
+// setIfGeneration 键的失效代数仍为generation时写入条目，返回是否写入
+func (c *lruCache) setIfGeneration(key, value string, ttl time.Duration, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[generationStripe(key)] != generation {
+		return false
+	}
+	c.setLocked(key, value, ttl)
+	return true
+}
+
+// setLocked 写入条目，调用方需持有锁
+func (c *lruCache) setLocked(key, value string, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// delete 删除条目
+func (c *lruCache) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		// 键不在本地缓存中也要递增代数，使正在进行的远端读取放弃回填
+		c.generations[generationStripe(key)]++
+		if elem, ok := c.items[key]; ok {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// purge 清空所有条目
+func (c *lruCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	for i := range c.generations {
+		c.generations[i]++
+	}
+}
+
+// len 获取条目数量
+func (c *lruCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// removeElement 删除链表元素，调用方需持有锁
+func (c *lruCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
+
+// generationStripe 计算键所在的代数分段
+func generationStripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % generationStripes)
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLRUCacheEviction 测试容量淘汰与过期
+func TestLRUCacheEviction(t *testing.T) {
+	lru := newLRUCache(2)
+	lru.set("a", "1", 0)
+	lru.set("b", "2", 0)
+
+	// 访问a使b成为最久未使用的条目
+	if _, ok := lru.get("a"); !ok {
+		t.Fatal("Expected key 'a' to exist")
+	}
+	lru.set("c", "3", 0)
+
+	if _, ok := lru.get("b"); ok {
+		t.Error("Expected key 'b' to be evicted")
+	}
+	if value, ok := lru.get("a"); !ok || value != "1" {
+		t.Errorf("Expected key 'a' to be '1', got %q (ok=%v)", value, ok)
+	}
+
+	lru.set("d", "4", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := lru.get("d"); ok {
+		t.Error("Expected key 'd' to expire")
+	}
+
+	lru.delete("a")
+	if lru.len() != 0 {
+		t.Errorf("Expected no entries left, got %d", lru.len())
+	}
+}
@@ -0,0 +1,980 @@
+package cache
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 与Redis返回一致的错误
+var (
+	errWrongType   = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger  = errors.New("ERR value is not an integer or out of range")
+	errHSetArgs    = errors.New("ERR wrong number of arguments for 'hset' command")
+	errMemoryClose = errors.New("cache: in-memory cache is closed")
+)
+
+// memoryKind 内存缓存值类型
+type memoryKind int
+
+const (
+	kindString memoryKind = iota
+	kindHash
+	kindList
+	kindSet
+	kindZSet
+)
+
+// memoryItem 内存缓存条目
+type memoryItem struct {
+	kind      memoryKind
+	str       string
+	hash      map[string]string
+	list      []string
+	set       map[string]struct{}
+	zset      map[string]float64
+	expiresAt time.Time
+}
+
+// expired 检查条目是否过期
+func (item *memoryItem) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && !now.Before(item.expiresAt)
+}
+
+// InMemoryCache 内存缓存实现，语义与Redis保持一致，用于测试或单机部署
+// Pipeline/TxPipeline 返回的管道在 Exec 时于内存中执行，只支持Cache接口涉及的命令
+type InMemoryCache struct {
+	mu     sync.Mutex
+	items  map[string]*memoryItem
+	tags   map[string]map[string]time.Time // 标签 -> 缓存键 -> 过期时间
+	client *redis.Client                   // 只用于构造管道，命令由memoryHook在内存中执行
+	closed bool
+	stop   chan struct{}
+}
+
+// NewInMemoryCache 创建内存缓存实例，后台定期清理过期键，使用完毕后需调用Close
+func NewInMemoryCache() *InMemoryCache {
+	m := &InMemoryCache{
+		items: make(map[string]*memoryItem),
+		tags:  make(map[string]map[string]time.Time),
+		stop:  make(chan struct{}),
+	}
+	m.client = redis.NewClient(&redis.Options{Addr: "in-memory"})
+	m.client.AddHook(memoryHook{cache: m})
+	go m.janitor(time.Minute)
+	return m
+}
+
+// janitor 定期清理过期键
+func (m *InMemoryCache) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			m.mu.Lock()
+			for key, item := range m.items {
+				if item.expired(now) {
+					delete(m.items, key)
+				}
+			}
+			m.pruneTags(now)
+			m.mu.Unlock()
+		}
+	}
+}
+
+// lookup 获取未过期的条目，调用方需持有锁
+func (m *InMemoryCache) lookup(key string) *memoryItem {
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if item.expired(time.Now()) {
+		delete(m.items, key)
+		return nil
+	}
+	return item
+}
+
+// lookupKind 获取指定类型的条目，类型不符时返回WRONGTYPE错误，调用方需持有锁
+func (m *InMemoryCache) lookupKind(key string, kind memoryKind) (*memoryItem, error) {
+	item := m.lookup(key)
+	if item == nil {
+		return nil, nil
+	}
+	if item.kind != kind {
+		return nil, errWrongType
+	}
+	return item, nil
+}
+
+// lookupOrCreate 获取或创建指定类型的条目，调用方需持有锁
+func (m *InMemoryCache) lookupOrCreate(key string, kind memoryKind) (*memoryItem, error) {
+	item, err := m.lookupKind(key, kind)
+	if err != nil || item != nil {
+		return item, err
+	}
+
+	item = &memoryItem{kind: kind}
+	switch kind {
+	case kindHash:
+		item.hash = make(map[string]string)
+	case kindSet:
+		item.set = make(map[string]struct{})
+	case kindZSet:
+		item.zset = make(map[string]float64)
+	}
+	m.items[key] = item
+	return item, nil
+}
+
+// removeIfEmpty 集合类型为空时删除键，与Redis行为一致，调用方需持有锁
+func (m *InMemoryCache) removeIfEmpty(key string, item *memoryItem) {
+	var size int
+	switch item.kind {
+	case kindHash:
+		size = len(item.hash)
+	case kindList:
+		size = len(item.list)
+	case kindSet:
+		size = len(item.set)
+	case kindZSet:
+		size = len(item.zset)
+	default:
+		return
+	}
+	if size == 0 {
+		delete(m.items, key)
+	}
+}
+
+// setString 写入字符串值，调用方需持有锁
+func (m *InMemoryCache) setString(key, value string, expiration time.Duration) {
+	item := &memoryItem{kind: kindString, str: value}
+	switch {
+	case expiration == redis.KeepTTL:
+		if existing := m.lookup(key); existing != nil {
+			item.expiresAt = existing.expiresAt
+		}
+	case expiration > 0:
+		item.expiresAt = time.Now().Add(expiration)
+	}
+	m.items[key] = item
+}
+
+// getString 读取字符串值，调用方需持有锁
+func (m *InMemoryCache) getString(key string) (string, error) {
+	item, err := m.lookupKind(key, kindString)
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		return "", ErrNotFound
+	}
+	return item.str, nil
+}
+
+// encode 编码缓存值，使用默认的JSON编解码器
+func (m *InMemoryCache) encode(value interface{}) ([]byte, error) {
+	return defaultSerializer.encode(value)
+}
+
+// decode 解码缓存值
+func (m *InMemoryCache) decode(data []byte, dest interface{}) error {
+	return defaultSerializer.decode(data, dest)
+}
+
+// Set 设置缓存
+func (m *InMemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
+	data, err := m.encode(value)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setString(key, string(data), expiration)
+
+	expiresAt := m.items[key].expiresAt
+	for _, tag := range applySetOptions(opts).tags {
+		members, ok := m.tags[tag]
+		if !ok {
+			members = make(map[string]time.Time)
+			m.tags[tag] = members
+		}
+		members[key] = expiresAt
+	}
+	return nil
+}
+
+// InvalidateTags 删除标签下的所有缓存键
+func (m *InMemoryCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			delete(m.items, key)
+		}
+		delete(m.tags, tag)
+	}
+	return nil
+}
+
+// pruneTags 清理标签中已过期的成员，调用方需持有锁
+func (m *InMemoryCache) pruneTags(now time.Time) {
+	for tag, members := range m.tags {
+		for key, expiresAt := range members {
+			if !expiresAt.IsZero() && !now.Before(expiresAt) {
+				delete(members, key)
+			}
+		}
+		if len(members) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
+
+// Get 获取缓存
+func (m *InMemoryCache) Get(ctx context.Context, key string, dest interface{}) error {
+	m.mu.Lock()
+	data, err := m.getString(key)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return m.decode([]byte(data), dest)
+}
+
+// Del 删除缓存
+func (m *InMemoryCache) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.del(keys)
+	return nil
+}
+
+// del 删除键并返回实际删除的数量，调用方需持有锁
+func (m *InMemoryCache) del(keys []string) int64 {
+	var count int64
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			delete(m.items, key)
+			count++
+		}
+	}
+	return count
+}
+
+// Exists 检查键是否存在，重复的键会被重复计数
+func (m *InMemoryCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.exists(keys), nil
+}
+
+// exists 统计存在的键数量，调用方需持有锁
+func (m *InMemoryCache) exists(keys []string) int64 {
+	var count int64
+	for _, key := range keys {
+		if m.lookup(key) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Expire 设置过期时间，非正数的过期时间会立即删除键
+func (m *InMemoryCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire(key, expiration)
+	return nil
+}
+
+// expire 设置过期时间，键不存在时返回false，调用方需持有锁
+func (m *InMemoryCache) expire(key string, expiration time.Duration) bool {
+	item := m.lookup(key)
+	if item == nil {
+		return false
+	}
+	if expiration <= 0 {
+		delete(m.items, key)
+		return true
+	}
+	item.expiresAt = time.Now().Add(expiration)
+	return true
+}
+
+// TTL 获取剩余生存时间，键不存在时返回-2，未设置过期时间时返回-1
+func (m *InMemoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remaining := m.remaining(key)
+	if remaining < 0 {
+		return remaining, nil
+	}
+	// Redis按秒四舍五入返回剩余时间
+	return (remaining + 500*time.Millisecond).Truncate(time.Second), nil
+}
+
+// remaining 获取未取整的剩余生存时间，键不存在时返回-2，未设置过期时间时返回-1，调用方需持有锁
+func (m *InMemoryCache) remaining(key string) time.Duration {
+	item := m.lookup(key)
+	if item == nil {
+		return -2
+	}
+	if item.expiresAt.IsZero() {
+		return -1
+	}
+	return time.Until(item.expiresAt)
+}
+
+// MGet 批量获取缓存，dest必须为 *map[string]T，只写入存在的键
+func (m *InMemoryCache) MGet(ctx context.Context, keys []string, dest interface{}) error {
+	raw := make(map[string]string, len(keys))
+	m.mu.Lock()
+	for _, key := range keys {
+		// 与Redis MGET一致，非字符串类型的键视为不存在
+		if item, err := m.lookupKind(key, kindString); err == nil && item != nil {
+			raw[key] = item.str
+		}
+	}
+	m.mu.Unlock()
+
+	return decodeMap(raw, dest, m.decode)
+}
+
+// MSet 批量设置缓存
+func (m *InMemoryCache) MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	encoded, err := encodeMap(values, m.encode)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, value := range encoded {
+		m.setString(key, value, expiration)
+	}
+	return nil
+}
+
+// MDel 批量删除缓存
+func (m *InMemoryCache) MDel(ctx context.Context, keys ...string) error {
+	return m.Del(ctx, keys...)
+}
+
+// SetString 设置字符串值
+func (m *InMemoryCache) SetString(ctx context.Context, key, value string, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setString(key, value, expiration)
+	return nil
+}
+
+// GetString 获取字符串值
+func (m *InMemoryCache) GetString(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.getString(key)
+}
+
+// incrBy 按增量修改整数值，保留原有过期时间，调用方需持有锁
+func (m *InMemoryCache) incrBy(key string, delta int64) (int64, error) {
+	item, err := m.lookupKind(key, kindString)
+	if err != nil {
+		return 0, err
+	}
+	if item == nil {
+		item = &memoryItem{kind: kindString, str: "0"}
+		m.items[key] = item
+	}
+
+	current, err := strconv.ParseInt(item.str, 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	current += delta
+	item.str = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+// Incr 递增
+func (m *InMemoryCache) Incr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.incrBy(key, 1)
+}
+
+// Decr 递减
+func (m *InMemoryCache) Decr(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.incrBy(key, -1)
+}
+
+// HSet 设置哈希字段，支持 "k1", "v1", "k2", "v2"、[]string、map[string]interface{} 和 map[string]string 形式
+func (m *InMemoryCache) HSet(ctx context.Context, key string, values ...interface{}) error {
+	pairs, err := hashPairs(values)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err = m.hset(key, pairs)
+	return err
+}
+
+// hset 写入字段值对并返回新增字段数量，调用方需持有锁
+func (m *InMemoryCache) hset(key string, pairs []string) (int64, error) {
+	item, err := m.lookupOrCreate(key, kindHash)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	for i := 0; i < len(pairs); i += 2 {
+		if _, ok := item.hash[pairs[i]]; !ok {
+			added++
+		}
+		item.hash[pairs[i]] = pairs[i+1]
+	}
+	return added, nil
+}
+
+// HGet 获取哈希字段值
+func (m *InMemoryCache) HGet(ctx context.Context, key, field string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.hget(key, field)
+}
+
+// hget 获取哈希字段值，调用方需持有锁
+func (m *InMemoryCache) hget(key, field string) (string, error) {
+	item, err := m.lookupKind(key, kindHash)
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		return "", ErrNotFound
+	}
+	value, ok := item.hash[field]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// HGetAll 获取所有哈希字段
+func (m *InMemoryCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.hgetAll(key)
+}
+
+// hgetAll 复制所有哈希字段，调用方需持有锁
+func (m *InMemoryCache) hgetAll(key string) (map[string]string, error) {
+	item, err := m.lookupKind(key, kindHash)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	if item != nil {
+		for field, value := range item.hash {
+			result[field] = value
+		}
+	}
+	return result, nil
+}
+
+// HDel 删除哈希字段
+func (m *InMemoryCache) HDel(ctx context.Context, key string, fields ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.hdel(key, fields)
+	return err
+}
+
+// hdel 删除哈希字段并返回实际删除的数量，调用方需持有锁
+func (m *InMemoryCache) hdel(key string, fields []string) (int64, error) {
+	item, err := m.lookupKind(key, kindHash)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	var count int64
+	for _, field := range fields {
+		if _, ok := item.hash[field]; ok {
+			delete(item.hash, field)
+			count++
+		}
+	}
+	m.removeIfEmpty(key, item)
+	return count, nil
+}
+
+// LPush 从左侧推入列表
+func (m *InMemoryCache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.push(key, formatArgs(values), true)
+	return err
+}
+
+// RPush 从右侧推入列表
+func (m *InMemoryCache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.push(key, formatArgs(values), false)
+	return err
+}
+
+// push 推入列表元素并返回列表长度，调用方需持有锁
+func (m *InMemoryCache) push(key string, values []string, left bool) (int64, error) {
+	item, err := m.lookupOrCreate(key, kindList)
+	if err != nil {
+		return 0, err
+	}
+	if left {
+		head := make([]string, 0, len(values)+len(item.list))
+		for i := len(values) - 1; i >= 0; i-- {
+			head = append(head, values[i])
+		}
+		item.list = append(head, item.list...)
+	} else {
+		item.list = append(item.list, values...)
+	}
+	return int64(len(item.list)), nil
+}
+
+// pop 弹出列表元素，调用方需持有锁
+func (m *InMemoryCache) pop(key string, left bool) (string, error) {
+	item, err := m.lookupKind(key, kindList)
+	if err != nil {
+		return "", err
+	}
+	if item == nil || len(item.list) == 0 {
+		return "", ErrNotFound
+	}
+
+	var value string
+	if left {
+		value = item.list[0]
+		item.list = item.list[1:]
+	} else {
+		value = item.list[len(item.list)-1]
+		item.list = item.list[:len(item.list)-1]
+	}
+	m.removeIfEmpty(key, item)
+	return value, nil
+}
+
+// LPop 从左侧弹出列表元素
+func (m *InMemoryCache) LPop(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.pop(key, true)
+}
+
+// RPop 从右侧弹出列表元素
+func (m *InMemoryCache) RPop(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.pop(key, false)
+}
+
+// LLen 获取列表长度
+func (m *InMemoryCache) LLen(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.llen(key)
+}
+
+// llen 获取列表长度，调用方需持有锁
+func (m *InMemoryCache) llen(key string) (int64, error) {
+	item, err := m.lookupKind(key, kindList)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	return int64(len(item.list)), nil
+}
+
+// SAdd 添加集合成员
+func (m *InMemoryCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.sadd(key, formatArgs(members))
+	return err
+}
+
+// sadd 添加集合成员并返回新增数量，调用方需持有锁
+func (m *InMemoryCache) sadd(key string, members []string) (int64, error) {
+	item, err := m.lookupOrCreate(key, kindSet)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	for _, member := range members {
+		if _, ok := item.set[member]; !ok {
+			item.set[member] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+// SRem 移除集合成员
+func (m *InMemoryCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.srem(key, formatArgs(members))
+	return err
+}
+
+// srem 移除集合成员并返回实际移除的数量，调用方需持有锁
+func (m *InMemoryCache) srem(key string, members []string) (int64, error) {
+	item, err := m.lookupKind(key, kindSet)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	var count int64
+	for _, member := range members {
+		if _, ok := item.set[member]; ok {
+			delete(item.set, member)
+			count++
+		}
+	}
+	m.removeIfEmpty(key, item)
+	return count, nil
+}
+
+// SMembers 获取集合所有成员，按字典序返回
+func (m *InMemoryCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.smembers(key)
+}
+
+// smembers 获取集合所有成员，按字典序返回，调用方需持有锁
+func (m *InMemoryCache) smembers(key string) ([]string, error) {
+	item, err := m.lookupKind(key, kindSet)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	if item != nil {
+		for member := range item.set {
+			members = append(members, member)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// SIsMember 检查是否为集合成员
+func (m *InMemoryCache) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sismember(key, formatArg(member))
+}
+
+// sismember 检查是否为集合成员，调用方需持有锁
+func (m *InMemoryCache) sismember(key, member string) (bool, error) {
+	item, err := m.lookupKind(key, kindSet)
+	if err != nil || item == nil {
+		return false, err
+	}
+	_, ok := item.set[member]
+	return ok, nil
+}
+
+// ZAdd 添加有序集合成员
+func (m *InMemoryCache) ZAdd(ctx context.Context, key string, members ...redis.Z) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.zadd(key, members)
+	return err
+}
+
+// zadd 添加或更新有序集合成员并返回新增数量，调用方需持有锁
+func (m *InMemoryCache) zadd(key string, members []redis.Z) (int64, error) {
+	item, err := m.lookupOrCreate(key, kindZSet)
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	for _, member := range members {
+		name := formatArg(member.Member)
+		if _, ok := item.zset[name]; !ok {
+			added++
+		}
+		item.zset[name] = member.Score
+	}
+	return added, nil
+}
+
+// ZRem 移除有序集合成员
+func (m *InMemoryCache) ZRem(ctx context.Context, key string, members ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.zrem(key, formatArgs(members))
+	return err
+}
+
+// zrem 移除有序集合成员并返回实际移除的数量，调用方需持有锁
+func (m *InMemoryCache) zrem(key string, members []string) (int64, error) {
+	item, err := m.lookupKind(key, kindZSet)
+	if err != nil || item == nil {
+		return 0, err
+	}
+	var count int64
+	for _, member := range members {
+		if _, ok := item.zset[member]; ok {
+			delete(item.zset, member)
+			count++
+		}
+	}
+	m.removeIfEmpty(key, item)
+	return count, nil
+}
+
+// zrange 按分数升序（分数相同时按成员字典序）获取范围内的成员，支持负数索引，调用方需持有锁
+func (m *InMemoryCache) zrange(key string, start, stop int64) ([]redis.Z, error) {
+	item, err := m.lookupKind(key, kindZSet)
+	if err != nil || item == nil {
+		return []redis.Z{}, err
+	}
+
+	sorted := make([]redis.Z, 0, len(item.zset))
+	for member, score := range item.zset {
+		sorted = append(sorted, redis.Z{Score: score, Member: member})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Score != sorted[j].Score {
+			return sorted[i].Score < sorted[j].Score
+		}
+		return sorted[i].Member.(string) < sorted[j].Member.(string)
+	})
+
+	from, to, ok := normalizeRange(start, stop, int64(len(sorted)))
+	if !ok {
+		return []redis.Z{}, nil
+	}
+	return sorted[from : to+1], nil
+}
+
+// ZRange 获取有序集合范围内的成员
+func (m *InMemoryCache) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members, err := m.zrange(key, start, stop)
+	if err != nil {
+		return nil, err
+	}
+	return zMembers(members), nil
+}
+
+// ZRangeWithScores 获取有序集合范围内的成员及分数
+func (m *InMemoryCache) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.zrange(key, start, stop)
+}
+
+// zMembers 提取有序集合成员名称
+func zMembers(members []redis.Z) []string {
+	result := make([]string, len(members))
+	for i, member := range members {
+		result[i] = member.Member.(string)
+	}
+	return result
+}
+
+// Pipeline 获取管道，命令在 Exec 时依次于内存中执行
+func (m *InMemoryCache) Pipeline() redis.Pipeliner {
+	return m.client.Pipeline()
+}
+
+// TxPipeline 获取事务管道，Exec 时在同一次加锁内执行全部命令
+func (m *InMemoryCache) TxPipeline() redis.Pipeliner {
+	return m.client.TxPipeline()
+}
+
+// Ping 测试连接
+func (m *InMemoryCache) Ping(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errMemoryClose
+	}
+	return nil
+}
+
+// Close 停止后台清理
+func (m *InMemoryCache) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.stop)
+		return m.client.Close()
+	}
+	return nil
+}
+
+// GetClient 内存缓存没有Redis客户端，返回nil
+func (m *InMemoryCache) GetClient() redis.UniversalClient {
+	return nil
+}
+
+// normalizeRange 将Redis风格的起止索引转换为切片下标
+func normalizeRange(start, stop, length int64) (int64, int64, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if length == 0 || start > stop {
+		return 0, 0, false
+	}
+	return start, stop, true
+}
+
+// hashPairs 将HSet参数展开为字段值对
+func hashPairs(values []interface{}) ([]string, error) {
+	var pairs []string
+	if len(values) == 1 {
+		switch v := values[0].(type) {
+		case []string:
+			pairs = append(pairs, v...)
+		case []interface{}:
+			for _, item := range v {
+				pairs = append(pairs, formatArg(item))
+			}
+		case map[string]interface{}:
+			for field, value := range v {
+				pairs = append(pairs, field, formatArg(value))
+			}
+		case map[string]string:
+			for field, value := range v {
+				pairs = append(pairs, field, value)
+			}
+		default:
+			return nil, errHSetArgs
+		}
+	} else {
+		for _, value := range values {
+			pairs = append(pairs, formatArg(value))
+		}
+	}
+
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return nil, errHSetArgs
+	}
+	return pairs, nil
+}
+
+// formatArgs 批量转换参数
+func formatArgs(values []interface{}) []string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		result[i] = formatArg(value)
+	}
+	return result
+}
+
+// formatArg 按go-redis的参数编码规则将值转换为字符串
+func formatArg(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return strconv.FormatInt(v.Nanoseconds(), 10)
+	case encoding.BinaryMarshaler:
+		if data, err := v.MarshalBinary(); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	errMemoryDial  = errors.New("cache: in-memory cache has no network connection")
+	errNotFloat    = errors.New("ERR value is not a valid float")
+	errSyntax      = errors.New("ERR syntax error")
+	errInvalidTime = errors.New("ERR invalid expire time in 'set' command")
+)
+
+// memoryHook 拦截go-redis客户端的命令和管道，在内存缓存中执行而不建立网络连接
+type memoryHook struct {
+	cache *InMemoryCache
+}
+
+func (h memoryHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errMemoryDial
+	}
+}
+
+func (h memoryHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return h.cache.exec([]redis.Cmder{cmd})
+	}
+}
+
+func (h memoryHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return h.cache.exec(cmds)
+	}
+}
+
+// exec 在同一次加锁内依次执行命令，与Redis管道一致返回第一个失败命令的错误
+// 所有命令在锁内执行，事务管道中的命令不会与其他操作交错
+func (m *InMemoryCache) exec(cmds []redis.Cmder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var first error
+	for _, cmd := range cmds {
+		m.process(cmd)
+		if err := cmd.Err(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// memoryArity 各命令的最少参数个数（含命令名）
+var memoryArity = map[string]int{
+	"ping": 1, "multi": 1, "exec": 1,
+	"get": 2, "set": 3, "del": 2, "exists": 2, "expire": 3, "pexpire": 3, "ttl": 2, "pttl": 2, "mget": 2,
+	"incr": 2, "decr": 2, "incrby": 3, "decrby": 3,
+	"hset": 4, "hget": 3, "hgetall": 2, "hdel": 3,
+	"lpush": 3, "rpush": 3, "lpop": 2, "rpop": 2, "llen": 2,
+	"sadd": 3, "srem": 3, "smembers": 2, "sismember": 3,
+	"zadd": 4, "zrem": 3, "zrange": 4,
+}
+
+// process 执行单条命令并写入结果，调用方需持有锁
+// 只支持Cache接口涉及的命令，其他命令返回不支持的错误
+func (m *InMemoryCache) process(cmd redis.Cmder) {
+	name := cmd.Name()
+	arity, ok := memoryArity[name]
+	if !ok {
+		cmd.SetErr(fmt.Errorf("cache: command %q is not supported by in-memory cache", name))
+		return
+	}
+	args := formatArgs(cmd.Args())
+	if len(args) < arity {
+		cmd.SetErr(fmt.Errorf("ERR wrong number of arguments for '%s' command", name))
+		return
+	}
+
+	var (
+		val interface{}
+		err error
+	)
+	switch name {
+	case "ping":
+		val = "PONG"
+	case "multi":
+		val = "OK"
+	case "exec":
+		val = []interface{}(nil)
+	case "get":
+		val, err = m.getString(args[1])
+	case "set":
+		val, err = m.processSet(args[1:])
+	case "del":
+		val = m.del(args[1:])
+	case "exists":
+		val = m.exists(args[1:])
+	case "expire", "pexpire":
+		var n int64
+		if n, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+			err = errNotInteger
+			break
+		}
+		unit := time.Second
+		if name == "pexpire" {
+			unit = time.Millisecond
+		}
+		val = m.expire(args[1], time.Duration(n)*unit)
+	case "ttl", "pttl":
+		remaining := m.remaining(args[1])
+		switch {
+		case remaining < 0:
+		case name == "ttl":
+			remaining = (remaining + 500*time.Millisecond).Truncate(time.Second)
+		default:
+			remaining = remaining.Truncate(time.Millisecond)
+		}
+		val = remaining
+	case "mget":
+		values := make([]interface{}, len(args)-1)
+		for i, key := range args[1:] {
+			// 非字符串类型的键视为不存在
+			if item, _ := m.lookupKind(key, kindString); item != nil {
+				values[i] = item.str
+			}
+		}
+		val = values
+	case "incr", "decr", "incrby", "decrby":
+		delta := int64(1)
+		if len(args) > 2 {
+			if delta, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+				err = errNotInteger
+				break
+			}
+		}
+		if strings.HasPrefix(name, "decr") {
+			delta = -delta
+		}
+		val, err = m.incrBy(args[1], delta)
+	case "hset":
+		if len(args)%2 != 0 {
+			err = errHSetArgs
+			break
+		}
+		val, err = m.hset(args[1], args[2:])
+	case "hget":
+		val, err = m.hget(args[1], args[2])
+	case "hgetall":
+		val, err = m.hgetAll(args[1])
+	case "hdel":
+		val, err = m.hdel(args[1], args[2:])
+	case "lpush", "rpush":
+		val, err = m.push(args[1], args[2:], name == "lpush")
+	case "lpop", "rpop":
+		if len(args) > 2 {
+			err = errSyntax
+			break
+		}
+		val, err = m.pop(args[1], name == "lpop")
+	case "llen":
+		val, err = m.llen(args[1])
+	case "sadd":
+		val, err = m.sadd(args[1], args[2:])
+	case "srem":
+		val, err = m.srem(args[1], args[2:])
+	case "smembers":
+		val, err = m.smembers(args[1])
+	case "sismember":
+		val, err = m.sismember(args[1], args[2])
+	case "zadd":
+		val, err = m.processZAdd(args[1], args[2:])
+	case "zrem":
+		val, err = m.zrem(args[1], args[2:])
+	case "zrange":
+		val, err = m.processZRange(args[1:])
+	}
+
+	if errors.Is(err, ErrNotFound) {
+		err = redis.Nil
+	}
+	if err != nil {
+		cmd.SetErr(err)
+		return
+	}
+	setCmdVal(cmd, val)
+}
+
+// processSet 执行 SET key value [EX seconds|PX milliseconds|KEEPTTL]
+func (m *InMemoryCache) processSet(args []string) (string, error) {
+	var expiration time.Duration
+	for i := 2; i < len(args); i++ {
+		switch option := strings.ToLower(args[i]); option {
+		case "keepttl":
+			expiration = redis.KeepTTL
+		case "ex", "px":
+			if i+1 >= len(args) {
+				return "", errSyntax
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return "", errNotInteger
+			}
+			if n <= 0 {
+				return "", errInvalidTime
+			}
+			expiration = time.Duration(n) * time.Second
+			if option == "px" {
+				expiration = time.Duration(n) * time.Millisecond
+			}
+		default:
+			return "", errSyntax
+		}
+	}
+	m.setString(args[0], args[1], expiration)
+	return "OK", nil
+}
+
+// processZAdd 执行 ZADD key score member [score member ...]
+func (m *InMemoryCache) processZAdd(key string, args []string) (int64, error) {
+	if len(args)%2 != 0 {
+		return 0, errSyntax
+	}
+	members := make([]redis.Z, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		score, err := strconv.ParseFloat(args[i], 64)
+		if err != nil {
+			return 0, errNotFloat
+		}
+		members = append(members, redis.Z{Score: score, Member: args[i+1]})
+	}
+	return m.zadd(key, members)
+}
+
+// processZRange 执行 ZRANGE key start stop [WITHSCORES]
+func (m *InMemoryCache) processZRange(args []string) (interface{}, error) {
+	start, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	stop, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	withScores := false
+	for _, option := range args[3:] {
+		if !strings.EqualFold(option, "withscores") {
+			return nil, errSyntax
+		}
+		withScores = true
+	}
+
+	members, err := m.zrange(args[0], start, stop)
+	if err != nil {
+		return nil, err
+	}
+	if withScores {
+		return members, nil
+	}
+	return zMembers(members), nil
+}
+
+// setCmdVal 按命令的结果类型写入值
+func setCmdVal(cmd redis.Cmder, val interface{}) {
+	ok := true
+	switch c := cmd.(type) {
+	case *redis.Cmd:
+		c.SetVal(val)
+	case *redis.StatusCmd:
+		var v string
+		v, ok = val.(string)
+		c.SetVal(v)
+	case *redis.StringCmd:
+		var v string
+		v, ok = val.(string)
+		c.SetVal(v)
+	case *redis.IntCmd:
+		var v int64
+		v, ok = val.(int64)
+		c.SetVal(v)
+	case *redis.BoolCmd:
+		var v bool
+		v, ok = val.(bool)
+		c.SetVal(v)
+	case *redis.DurationCmd:
+		var v time.Duration
+		v, ok = val.(time.Duration)
+		c.SetVal(v)
+	case *redis.SliceCmd:
+		var v []interface{}
+		v, ok = val.([]interface{})
+		c.SetVal(v)
+	case *redis.StringSliceCmd:
+		var v []string
+		v, ok = val.([]string)
+		c.SetVal(v)
+	case *redis.MapStringStringCmd:
+		var v map[string]string
+		v, ok = val.(map[string]string)
+		c.SetVal(v)
+	case *redis.ZSliceCmd:
+		var v []redis.Z
+		v, ok = val.([]redis.Z)
+		c.SetVal(v)
+	default:
+		ok = false
+	}
+	if !ok {
+		cmd.SetErr(fmt.Errorf("cache: unexpected result %T for %T in in-memory cache", val, cmd))
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Script 通过EVALSHA执行的Lua脚本
+type Script struct {
+	name string
+	src  string
+	hash string
+}
+
+// NewScript 创建Lua脚本
+func NewScript(name, src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{name: name, src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+// Name 获取脚本名称
+func (s *Script) Name() string {
+	return s.name
+}
+
+// Hash 获取脚本SHA1
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Load 通过SCRIPT LOAD加载脚本，集群模式下会加载到所有主节点
+func (s *Script) Load(ctx context.Context, client redis.Scripter) error {
+	if err := client.ScriptLoad(ctx, s.src).Err(); err != nil {
+		return fmt.Errorf("failed to load script %s: %w", s.name, err)
+	}
+	return nil
+}
+
+// Run 通过EVALSHA执行脚本，节点上没有缓存脚本(NOSCRIPT)时回退到EVAL，EVAL会同时缓存脚本
+func (s *Script) Run(ctx context.Context, client redis.Scripter, keys []string, args ...interface{}) *redis.Cmd {
+	cmd := client.EvalSha(ctx, s.hash, keys, args...)
+	if err := cmd.Err(); err != nil && redis.HasErrorPrefix(err, "NOSCRIPT") {
+		return client.Eval(ctx, s.src, keys, args...)
+	}
+	return cmd
+}
+
+// ScriptRegistry Lua脚本注册表
+type ScriptRegistry struct {
+	mu      sync.RWMutex
+	scripts map[string]*Script
+}
+
+// NewScriptRegistry 创建脚本注册表
+func NewScriptRegistry() *ScriptRegistry {
+	return &ScriptRegistry{scripts: make(map[string]*Script)}
+}
+
+// Register 注册脚本，同名脚本会被覆盖
+func (r *ScriptRegistry) Register(name, src string) *Script {
+	script := NewScript(name, src)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[name] = script
+	return script
+}
+
+// Script 按名称获取脚本
+func (r *ScriptRegistry) Script(name string) (*Script, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	script, ok := r.scripts[name]
+	return script, ok
+}
+
+// Load 预加载所有已注册的脚本
+func (r *ScriptRegistry) Load(ctx context.Context, client redis.Scripter) error {
+	r.mu.RLock()
+	scripts := make([]*Script, 0, len(r.scripts))
+	for _, script := range r.scripts {
+		scripts = append(scripts, script)
+	}
+	r.mu.RUnlock()
+
+	for _, script := range scripts {
+		if err := script.Load(ctx, client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 全局脚本注册表，cache、lock、ratelimit等包的脚本都注册在这里
+var defaultScripts = NewScriptRegistry()
+
+// RegisterScript 在全局注册表中注册脚本，通常在包级变量初始化时调用
+func RegisterScript(name, src string) *Script {
+	return defaultScripts.Register(name, src)
+}
+
+// LoadScripts 预加载全局注册表中的所有脚本，可在服务启动时调用以避免首次执行的回退开销
+func LoadScripts(ctx context.Context, client redis.Scripter) error {
+	return defaultScripts.Load(ctx, client)
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+// TestScriptRunFallsBackToEval 测试脚本未缓存时回退到EVAL
+func TestScriptRunFallsBackToEval(t *testing.T) {
+	c := newTestRedisCache(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	registry := NewScriptRegistry()
+	script := registry.Register("test:echo", `return ARGV[1] .. ':' .. KEYS[1]`)
+	if err := c.GetClient().ScriptFlush(ctx).Err(); err != nil {
+		t.Skipf("SCRIPT FLUSH not supported: %v", err)
+	}
+
+	value, err := script.Run(ctx, c.GetClient(), []string{"k"}, "v").Text()
+	if err != nil || value != "v:k" {
+		t.Fatalf("Expected 'v:k', got %q (err=%v)", value, err)
+	}
+
+	if err := registry.Load(ctx, c.GetClient()); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	exists, err := c.GetClient().ScriptExists(ctx, script.Hash()).Result()
+	if err != nil || len(exists) != 1 || !exists[0] {
+		t.Errorf("Expected script to be loaded, got %v (err=%v)", exists, err)
+	}
+}
@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix 标签键前缀，标签键为有序集合，成员为缓存键，分数为缓存键的过期时间(毫秒时间戳)
+const tagKeyPrefix = "vgo:cache:tag:"
+
+// tagKey 获取标签对应的Redis键
+func tagKey(tag string) string {
+	return tagKeyPrefix + tag
+}
+
+// SetOption 设置缓存选项
+type SetOption func(*setOptions)
+
+// setOptions 设置缓存选项集合
+type setOptions struct {
+	tags []string
+}
+
+// WithTags 为缓存键关联标签，调用 InvalidateTags 时会删除标签下的所有键
+// 之后不带标签(或带其他标签)重新写入同一个键不会将其移出原有标签，该键仍会被原标签的 InvalidateTags 删除，
+// 直到写入时的过期时间到达后才从标签中清理
+func WithTags(tags ...string) SetOption {
+	return func(o *setOptions) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// applySetOptions 合并设置选项
+func applySetOptions(opts []SetOption) setOptions {
+	var o setOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// tagMembership 将member加入KEYS[first..n]对应的标签，并清理已过期的成员；
+// 标签键的过期时间与最晚过期的成员一致，成员都不过期时标签键也不过期
+const tagMembership = `
+local score = '+inf'
+if ttl > 0 then
+	score = now + ttl
+end
+for i = first, #KEYS do
+	redis.call('ZREMRANGEBYSCORE', KEYS[i], '-inf', '(' .. now)
+	redis.call('ZADD', KEYS[i], score, member)
+	local last = redis.call('ZRANGE', KEYS[i], -1, -1, 'WITHSCORES')
+	if last[2] == 'inf' then
+		redis.call('PERSIST', KEYS[i])
+	else
+		redis.call('PEXPIREAT', KEYS[i], last[2])
+	end
+end
+return 1
+`
+
+// setTaggedScript 原子地写入缓存值并关联标签
+// KEYS[1]: 缓存键, KEYS[2..n]: 标签键; ARGV[1]: 值, ARGV[2]: 过期时间(毫秒，0表示不过期，-1表示保留原有过期时间), ARGV[3]: 当前时间(毫秒)
+var setTaggedScript = RegisterScript("cache:set_tagged", `
+local member = KEYS[1]
+local first = 2
+local expiration = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+if expiration > 0 then
+	redis.call('SET', member, ARGV[1], 'PX', expiration)
+elseif expiration == -1 then
+	redis.call('SET', member, ARGV[1], 'KEEPTTL')
+else
+	redis.call('SET', member, ARGV[1])
+end
+local ttl = redis.call('PTTL', member)
+`+tagMembership)
+
+// addTagScript 将缓存键加入标签，集群模式下缓存键与标签键不在同一slot时使用
+// KEYS[1..n]: 标签键; ARGV[1]: 缓存键, ARGV[2]: 过期时间(毫秒，0表示不过期), ARGV[3]: 当前时间(毫秒)
+var addTagScript = RegisterScript("cache:add_tag", `
+local member = ARGV[1]
+local first = 1
+local ttl = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+`+tagMembership)
+
+// invalidateTagsScript 删除标签下的所有缓存键以及标签本身，返回被删除的缓存键
+// KEYS[1..n]: 标签键
+var invalidateTagsScript = RegisterScript("cache:invalidate_tags", `
+local deleted = {}
+for i = 1, #KEYS do
+	local members = redis.call('ZRANGE', KEYS[i], 0, -1)
+	for j = 1, #members, 1000 do
+		redis.call('DEL', unpack(members, j, math.min(j + 999, #members)))
+	end
+	for _, member in ipairs(members) do
+		deleted[#deleted + 1] = member
+	end
+	redis.call('DEL', KEYS[i])
+end
+return deleted
+`)
+
+// setTagged 写入缓存值并关联标签。非集群模式下通过一个脚本原子完成；
+// 集群模式下缓存键与标签键可能位于不同slot，先写入值再逐个更新标签
+func (r *RedisCache) setTagged(ctx context.Context, key string, value interface{}, expiration time.Duration, tags []string) error {
+	ttl := expiration.Milliseconds()
+	switch {
+	case expiration == redis.KeepTTL:
+		ttl = -1
+	case expiration > 0 && ttl == 0:
+		// 不足1毫秒的过期时间向上取整，避免被当作不过期
+		ttl = 1
+	case ttl < 0:
+		ttl = 0
+	}
+	now := time.Now().UnixMilli()
+
+	if _, ok := r.client.(*redis.ClusterClient); !ok {
+		keys := make([]string, 0, len(tags)+1)
+		keys = append(keys, key)
+		for _, tag := range tags {
+			keys = append(keys, tagKey(tag))
+		}
+		return setTaggedScript.Run(ctx, r.client, keys, value, ttl, now).Err()
+	}
+
+	if err := r.client.Set(ctx, key, value, expiration).Err(); err != nil {
+		return err
+	}
+	if ttl == -1 {
+		remaining, err := r.client.PTTL(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		ttl = max(remaining.Milliseconds(), 0)
+	}
+	for _, tag := range tags {
+		if err := addTagScript.Run(ctx, r.client, []string{tagKey(tag)}, key, ttl, now).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidateTags 删除标签下的所有缓存键，返回被删除的缓存键。
+// 非集群模式下通过一个脚本原子完成；集群模式下逐个标签读取成员后按slot分组删除
+func (r *RedisCache) invalidateTags(ctx context.Context, tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = tagKey(tag)
+	}
+
+	if _, ok := r.client.(*redis.ClusterClient); !ok {
+		return invalidateTagsScript.Run(ctx, r.client, keys).StringSlice()
+	}
+
+	var deleted []string
+	for _, key := range keys {
+		members, err := r.client.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if err := r.MDel(ctx, members...); err != nil {
+			return deleted, err
+		}
+		if err := r.client.Del(ctx, key).Err(); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, members...)
+	}
+	return deleted, nil
+}
+
+// InvalidateTags 删除标签下的所有缓存键
+func (r *RedisCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	_, err := r.invalidateTags(ctx, tags)
+	return err
+}
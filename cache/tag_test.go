@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestTagKeyExpiresWithMembers 测试标签键的过期时间跟随最晚过期的成员
+func TestTagKeyExpiresWithMembers(t *testing.T) {
+	c := newTestRedisCache(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	tag := "test:" + uuid.NewString()
+	key := "tagged:" + uuid.NewString()
+	defer c.Del(ctx, key, tagKey(tag))
+
+	if err := c.Set(ctx, key, 1, time.Minute, WithTags(tag)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if ttl, err := c.GetClient().PTTL(ctx, tagKey(tag)).Result(); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected tag key to expire with its member, got %v (err=%v)", ttl, err)
+	}
+
+	if err := c.Set(ctx, key+":persistent", 1, 0, WithTags(tag)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	defer c.Del(ctx, key+":persistent")
+	if ttl, _ := c.GetClient().PTTL(ctx, tagKey(tag)).Result(); ttl != -1 {
+		t.Errorf("Expected tag key without expiration, got %v", ttl)
+	}
+}
+
+// TestSetTaggedSubMillisecond 测试不足1毫秒的过期时间不会被当作不过期
+func TestSetTaggedSubMillisecond(t *testing.T) {
+	c := newTestRedisCache(t)
+	defer c.Close()
+	ctx := context.Background()
+
+	tag := "test:" + uuid.NewString()
+	key := "tagged:" + uuid.NewString()
+	defer c.Del(ctx, key, tagKey(tag))
+
+	if err := c.Set(ctx, key, 1, 500*time.Microsecond, WithTags(tag)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if ttl, _ := c.GetClient().PTTL(ctx, key).Result(); ttl == -1 {
+		t.Error("Expected sub-millisecond expiration to be rounded up, got no expiration")
+	}
+}
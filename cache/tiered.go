@@ -0,0 +1,449 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+)
+
+// 缓存层级名称，用于指标标签
+const (
+	TierLocal = "local"
+	TierRedis = "redis"
+)
+
+// TieredConfig 二级缓存配置
+type TieredConfig struct {
+	MaxEntries int           `mapstructure:"max_entries"` // 本地缓存最大条目数
+	LocalTTL   time.Duration `mapstructure:"local_ttl"`   // 本地缓存最长存活时间，广播丢失时限制数据陈旧时长
+	Channel    string        `mapstructure:"channel"`     // 失效广播的pub/sub频道
+}
+
+// DefaultTieredConfig 默认二级缓存配置
+func DefaultTieredConfig() *TieredConfig {
+	return &TieredConfig{
+		MaxEntries: 10000,
+		LocalTTL:   time.Minute,
+		Channel:    "vgo:cache:invalidate",
+	}
+}
+
+// invalidationMessage 失效广播消息
+type invalidationMessage struct {
+	Source string   `json:"source"`
+	Keys   []string `json:"keys"`
+}
+
+// TieredCache 二级缓存实现
+// 在RedisCache之前增加进程内LRU缓存，键被修改或删除时通过Redis pub/sub通知所有实例清除本地副本。
+// 本地层只缓存字符串类型的值（Set/SetString写入的值），哈希、列表等结构直接访问Redis；
+// 通过Pipeline/TxPipeline写入的键不会触发失效广播。
+type TieredCache struct {
+	remote     *RedisCache
+	local      *lruCache
+	config     TieredConfig
+	metrics    metrics.CacheMetrics
+	instanceID string
+	pubsub     *redis.PubSub
+	done       chan struct{}
+}
+
+// NewTieredCache 创建二级缓存实例，collector为nil或未实现 metrics.CacheMetrics 时不记录指标
+// collector记录本地层和Redis层的命中情况，remote通过 WithMetrics 使用同一收集器时不会重复记录
+func NewTieredCache(remote *RedisCache, config *TieredConfig, collector metrics.MetricsCollector) (*TieredCache, error) {
+	if remote == nil {
+		return nil, fmt.Errorf("redis cache is required")
+	}
+	if config == nil {
+		config = DefaultTieredConfig()
+	}
+	if config.LocalTTL <= 0 {
+		config.LocalTTL = time.Minute
+	}
+	if config.Channel == "" {
+		config.Channel = "vgo:cache:invalidate"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// LazyConnect时不等待订阅确认，连接建立后自动订阅
+	pubsub := remote.client.Subscribe(ctx, config.Channel)
+	if !remote.config.LazyConnect {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			pubsub.Close()
+			return nil, fmt.Errorf("failed to subscribe invalidation channel: %w", err)
+		}
+	}
+
+	t := &TieredCache{
+		remote:     remote,
+		local:      newLRUCache(config.MaxEntries),
+		config:     *config,
+		metrics:    cacheMetrics(collector),
+		instanceID: uuid.NewString(),
+		pubsub:     pubsub,
+		done:       make(chan struct{}),
+	}
+	go t.listen()
+
+	return t, nil
+}
+
+// listen 监听失效广播并清除本地副本
+func (t *TieredCache) listen() {
+	defer close(t.done)
+
+	for msg := range t.pubsub.Channel() {
+		var message invalidationMessage
+		if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+			continue
+		}
+		if message.Source == t.instanceID {
+			continue
+		}
+		t.local.delete(message.Keys...)
+	}
+}
+
+// invalidate 清除本地副本并广播给其他实例
+func (t *TieredCache) invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	t.local.delete(keys...)
+
+	payload, err := json.Marshal(invalidationMessage{Source: t.instanceID, Keys: keys})
+	if err != nil {
+		return
+	}
+	t.remote.client.Publish(ctx, t.config.Channel, payload)
+}
+
+// recordAccess 记录缓存命中指标
+func (t *TieredCache) recordAccess(tier string, hit bool) {
+	if t.metrics != nil {
+		t.metrics.RecordCacheAccess(tier, hit)
+	}
+}
+
+// getRaw 依次从本地缓存和Redis读取原始值
+func (t *TieredCache) getRaw(ctx context.Context, key string) (string, error) {
+	if value, ok := t.local.get(key); ok {
+		t.recordAccess(TierLocal, true)
+		return value, nil
+	}
+	t.recordAccess(TierLocal, false)
+
+	values, err := t.fetch(ctx, []string{key})
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+// fetch 从Redis读取本地未命中的键并回填本地缓存
+// 本地副本的存活时间不超过键在Redis中的剩余生存时间；读取期间键被失效时不回填，避免写回旧值
+func (t *TieredCache) fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	generations := make([]uint64, len(keys))
+	for i, key := range keys {
+		generations[i] = t.local.generation(key)
+	}
+	if t.metrics != nil {
+		ctx = withAccessRecorded(ctx)
+	}
+
+	values, ttls, err := t.remote.mgetRawTTL(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		value, ok := values[key]
+		t.recordAccess(TierRedis, ok)
+		if !ok {
+			continue
+		}
+		t.local.setIfGeneration(key, value, t.localTTL(ttls[key]), generations[i])
+	}
+	return values, nil
+}
+
+// localTTL 本地副本的存活时间，remaining为键在Redis中的剩余生存时间，0表示没有过期时间
+func (t *TieredCache) localTTL(remaining time.Duration) time.Duration {
+	if remaining > 0 && remaining < t.config.LocalTTL {
+		return remaining
+	}
+	return t.config.LocalTTL
+}
+
+// setRaw 写入Redis并更新本地缓存
+func (t *TieredCache) setRaw(ctx context.Context, key string, value string, expiration time.Duration, tags []string) error {
+	var err error
+	if len(tags) > 0 {
+		err = t.remote.setTagged(ctx, key, value, expiration, tags)
+	} else {
+		err = t.remote.client.Set(ctx, key, value, expiration).Err()
+	}
+	if err != nil {
+		t.local.delete(key)
+		return err
+	}
+
+	t.invalidate(ctx, key)
+	t.local.set(key, value, t.localTTL(expiration))
+	return nil
+}
+
+// Set 设置缓存
+func (t *TieredCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration, opts ...SetOption) error {
+	data, err := t.remote.encode(value)
+	if err != nil {
+		return err
+	}
+	return t.setRaw(ctx, key, string(data), expiration, applySetOptions(opts).tags)
+}
+
+// InvalidateTags 删除标签下的所有缓存键，并通知其他实例清除本地副本
+func (t *TieredCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	keys, err := t.remote.invalidateTags(ctx, tags)
+	t.invalidate(ctx, keys...)
+	return err
+}
+
+// Get 获取缓存
+func (t *TieredCache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := t.getRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	return t.remote.decode([]byte(data), dest)
+}
+
+// Del 删除缓存
+func (t *TieredCache) Del(ctx context.Context, keys ...string) error {
+	err := t.remote.Del(ctx, keys...)
+	t.invalidate(ctx, keys...)
+	return err
+}
+
+// Exists 检查键是否存在
+func (t *TieredCache) Exists(ctx context.Context, keys ...string) (int64, error) {
+	return t.remote.Exists(ctx, keys...)
+}
+
+// Expire 设置过期时间
+func (t *TieredCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	err := t.remote.Expire(ctx, key, expiration)
+	t.invalidate(ctx, key)
+	return err
+}
+
+// TTL 获取剩余生存时间
+func (t *TieredCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return t.remote.TTL(ctx, key)
+}
+
+// MGet 批量获取缓存，先读本地缓存，未命中的键批量从Redis读取
+func (t *TieredCache) MGet(ctx context.Context, keys []string, dest interface{}) error {
+	raw := make(map[string]string, len(keys))
+	var misses []string
+	for _, key := range keys {
+		if value, ok := t.local.get(key); ok {
+			t.recordAccess(TierLocal, true)
+			raw[key] = value
+			continue
+		}
+		t.recordAccess(TierLocal, false)
+		misses = append(misses, key)
+	}
+
+	if len(misses) > 0 {
+		remote, err := t.fetch(ctx, misses)
+		if err != nil {
+			return err
+		}
+		for key, value := range remote {
+			raw[key] = value
+		}
+	}
+
+	return decodeMap(raw, dest, t.remote.decode)
+}
+
+// MSet 批量设置缓存
+func (t *TieredCache) MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	encoded, err := encodeMap(values, t.remote.encode)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(encoded))
+	for key := range encoded {
+		keys = append(keys, key)
+	}
+	err = t.remote.msetRaw(ctx, encoded, expiration)
+	t.invalidate(ctx, keys...)
+	return err
+}
+
+// MDel 批量删除缓存
+func (t *TieredCache) MDel(ctx context.Context, keys ...string) error {
+	err := t.remote.MDel(ctx, keys...)
+	t.invalidate(ctx, keys...)
+	return err
+}
+
+// SetString 设置字符串值
+func (t *TieredCache) SetString(ctx context.Context, key, value string, expiration time.Duration) error {
+	return t.setRaw(ctx, key, value, expiration, nil)
+}
+
+// GetString 获取字符串值
+func (t *TieredCache) GetString(ctx context.Context, key string) (string, error) {
+	return t.getRaw(ctx, key)
+}
+
+// Incr 递增
+func (t *TieredCache) Incr(ctx context.Context, key string) (int64, error) {
+	value, err := t.remote.Incr(ctx, key)
+	t.invalidate(ctx, key)
+	return value, err
+}
+
+// Decr 递减
+func (t *TieredCache) Decr(ctx context.Context, key string) (int64, error) {
+	value, err := t.remote.Decr(ctx, key)
+	t.invalidate(ctx, key)
+	return value, err
+}
+
+// HSet 设置哈希字段
+func (t *TieredCache) HSet(ctx context.Context, key string, values ...interface{}) error {
+	return t.remote.HSet(ctx, key, values...)
+}
+
+// HGet 获取哈希字段值
+func (t *TieredCache) HGet(ctx context.Context, key, field string) (string, error) {
+	return t.remote.HGet(ctx, key, field)
+}
+
+// HGetAll 获取所有哈希字段
+func (t *TieredCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return t.remote.HGetAll(ctx, key)
+}
+
+// HDel 删除哈希字段
+func (t *TieredCache) HDel(ctx context.Context, key string, fields ...string) error {
+	return t.remote.HDel(ctx, key, fields...)
+}
+
+// LPush 从左侧推入列表
+func (t *TieredCache) LPush(ctx context.Context, key string, values ...interface{}) error {
+	return t.remote.LPush(ctx, key, values...)
+}
+
+// RPush 从右侧推入列表
+func (t *TieredCache) RPush(ctx context.Context, key string, values ...interface{}) error {
+	return t.remote.RPush(ctx, key, values...)
+}
+
+// LPop 从左侧弹出列表元素
+func (t *TieredCache) LPop(ctx context.Context, key string) (string, error) {
+	return t.remote.LPop(ctx, key)
+}
+
+// RPop 从右侧弹出列表元素
+func (t *TieredCache) RPop(ctx context.Context, key string) (string, error) {
+	return t.remote.RPop(ctx, key)
+}
+
+// LLen 获取列表长度
+func (t *TieredCache) LLen(ctx context.Context, key string) (int64, error) {
+	return t.remote.LLen(ctx, key)
+}
+
+// SAdd 添加集合成员
+func (t *TieredCache) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return t.remote.SAdd(ctx, key, members...)
+}
+
+// SRem 移除集合成员
+func (t *TieredCache) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return t.remote.SRem(ctx, key, members...)
+}
+
+// SMembers 获取集合所有成员
+func (t *TieredCache) SMembers(ctx context.Context, key string) ([]string, error) {
+	return t.remote.SMembers(ctx, key)
+}
+
+// SIsMember 检查是否为集合成员
+func (t *TieredCache) SIsMember(ctx context.Context, key string, member interface{}) (bool, error) {
+	return t.remote.SIsMember(ctx, key, member)
+}
+
+// ZAdd 添加有序集合成员
+func (t *TieredCache) ZAdd(ctx context.Context, key string, members ...redis.Z) error {
+	return t.remote.ZAdd(ctx, key, members...)
+}
+
+// ZRem 移除有序集合成员
+func (t *TieredCache) ZRem(ctx context.Context, key string, members ...interface{}) error {
+	return t.remote.ZRem(ctx, key, members...)
+}
+
+// ZRange 获取有序集合范围内的成员
+func (t *TieredCache) ZRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	return t.remote.ZRange(ctx, key, start, stop)
+}
+
+// ZRangeWithScores 获取有序集合范围内的成员及分数
+func (t *TieredCache) ZRangeWithScores(ctx context.Context, key string, start, stop int64) ([]redis.Z, error) {
+	return t.remote.ZRangeWithScores(ctx, key, start, stop)
+}
+
+// Pipeline 获取管道，通过管道写入的键不会清除本地缓存
+func (t *TieredCache) Pipeline() redis.Pipeliner {
+	return t.remote.Pipeline()
+}
+
+// TxPipeline 获取事务管道，通过管道写入的键不会清除本地缓存
+func (t *TieredCache) TxPipeline() redis.Pipeliner {
+	return t.remote.TxPipeline()
+}
+
+// Ping 测试连接
+func (t *TieredCache) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
+
+// Close 停止失效监听并关闭Redis连接
+func (t *TieredCache) Close() error {
+	err := t.pubsub.Close()
+	<-t.done
+	t.local.purge()
+	if closeErr := t.remote.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
+
+// GetClient 获取原始Redis客户端
+func (t *TieredCache) GetClient() redis.UniversalClient {
+	return t.remote.GetClient()
+}
+
+// LocalLen 获取本地缓存条目数量
+func (t *TieredCache) LocalLen() int {
+	return t.local.len()
+}
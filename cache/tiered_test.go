@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+)
+
+// tierMetrics 按层级记录命中情况的收集器
+type tierMetrics struct {
+	metrics.MetricsCollector
+	metrics.CacheMetrics
+	mu     sync.Mutex
+	access map[string]int
+}
+
+func (m *tierMetrics) RecordCacheAccess(tier string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.access[tier+":hit"]++
+	} else {
+		m.access[tier+":miss"]++
+	}
+}
+
+func (m *tierMetrics) RecordCacheCommand(command string, duration time.Duration, err error) {}
+
+func (m *tierMetrics) count(name string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.access[name]
+}
+
+// newTestTieredCache 创建连接到miniredis的二级缓存
+func newTestTieredCache(t *testing.T, addr string, collector metrics.MetricsCollector) *TieredCache {
+	t.Helper()
+	var opts []Option
+	if collector != nil {
+		opts = append(opts, WithMetrics(collector))
+	}
+	remote, err := NewRedisCache(&CacheConfig{Addr: addr, PoolStatsInterval: time.Hour}, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	tiered, err := NewTieredCache(remote, &TieredConfig{LocalTTL: time.Minute}, collector)
+	if err != nil {
+		remote.Close()
+		t.Fatalf("Failed to create tiered cache: %v", err)
+	}
+	t.Cleanup(func() { tiered.Close() })
+	return tiered
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestTieredCacheInvalidation 测试本地缓存命中、跨实例失效和按层级记录指标
+func TestTieredCacheInvalidation(t *testing.T) {
+	server := miniredis.RunT(t)
+	collector := &tierMetrics{access: make(map[string]int)}
+	a := newTestTieredCache(t, server.Addr(), nil)
+	b := newTestTieredCache(t, server.Addr(), collector)
+	ctx := context.Background()
+
+	generation := b.local.generation("k")
+	if err := a.SetString(ctx, "k", "v1", 0); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	// 等待写入产生的失效广播到达b，避免其清除b随后回填的本地副本
+	waitFor(t, func() bool { return b.local.generation("k") != generation })
+	for i := 0; i < 2; i++ {
+		if value, err := b.GetString(ctx, "k"); err != nil || value != "v1" {
+			t.Fatalf("Expected v1, got %q (err=%v)", value, err)
+		}
+	}
+	if _, err := b.GetString(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	// remote与二级缓存使用同一收集器时Redis层不重复记录
+	if collector.count("local:hit") != 1 || collector.count("redis:hit") != 1 || collector.count("redis:miss") != 1 {
+		t.Errorf("Unexpected access metrics %v", collector.access)
+	}
+
+	if err := a.SetString(ctx, "k", "v2", 0); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	waitFor(t, func() bool { return b.LocalLen() == 0 })
+	if value, err := b.GetString(ctx, "k"); err != nil || value != "v2" {
+		t.Errorf("Expected v2 after invalidation, got %q (err=%v)", value, err)
+	}
+}
+
+// TestTieredCacheLocalTTL 测试本地副本的存活时间不超过键在Redis中的剩余时间
+func TestTieredCacheLocalTTL(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server.Addr(), nil)
+	ctx := context.Background()
+
+	server.Set("short", `"v"`)
+	server.SetTTL("short", 2*time.Second)
+	server.Set("persistent", `"v"`)
+	var values map[string]string
+	if err := c.MGet(ctx, []string{"short", "persistent"}, &values); err != nil {
+		t.Fatalf("MGet failed: %v", err)
+	}
+
+	c.local.mu.Lock()
+	defer c.local.mu.Unlock()
+	short := time.Until(c.local.items["short"].Value.(*lruEntry).expiresAt)
+	if short <= 0 || short > 2*time.Second {
+		t.Errorf("Expected local ttl capped to 2s, got %v", short)
+	}
+	persistent := time.Until(c.local.items["persistent"].Value.(*lruEntry).expiresAt)
+	if persistent <= 2*time.Second {
+		t.Errorf("Expected local ttl for persistent key to use LocalTTL, got %v", persistent)
+	}
+}
+
+// invalidatingHook 在远端读取返回前触发失效，模拟读取期间收到失效广播
+type invalidatingHook struct {
+	local *lruCache
+}
+
+func (h *invalidatingHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *invalidatingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook { return next }
+
+func (h *invalidatingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		h.local.delete("k")
+		return err
+	}
+}
+
+// TestTieredCacheInvalidationDuringFetch 测试读取期间键被失效时不回填旧值
+func TestTieredCacheInvalidationDuringFetch(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := newTestTieredCache(t, server.Addr(), nil)
+	c.remote.client.AddHook(&invalidatingHook{local: c.local})
+
+	server.Set("k", "stale")
+	if value, err := c.GetString(context.Background(), "k"); err != nil || value != "stale" {
+		t.Fatalf("Expected stale, got %q (err=%v)", value, err)
+	}
+	if c.LocalLen() != 0 {
+		t.Error("Expected value read before invalidation not to be cached locally")
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Typed 泛型缓存封装
+// 在Cache接口之上提供类型安全的读写，键不存在时返回 found=false 而不是错误
+type Typed[T any] struct {
+	cache Cache
+	keys  *KeyBuilder
+}
+
+// NewTyped 创建泛型缓存，keys为nil时直接使用调用方传入的键
+func NewTyped[T any](cache Cache, keys *KeyBuilder) *Typed[T] {
+	return &Typed[T]{
+		cache: cache,
+		keys:  keys,
+	}
+}
+
+// Key 构建完整的缓存键
+func (t *Typed[T]) Key(key string) string {
+	if t.keys == nil {
+		return key
+	}
+	return t.keys.Key(key)
+}
+
+// Get 获取缓存值
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, bool, error) {
+	var value T
+	if err := t.cache.Get(ctx, t.Key(key), &value); err != nil {
+		var zero T
+		if errors.Is(err, ErrNotFound) {
+			return zero, false, nil
+		}
+		return zero, false, err
+	}
+	return value, true, nil
+}
+
+// Set 设置缓存值
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, expiration time.Duration, opts ...SetOption) error {
+	return t.cache.Set(ctx, t.Key(key), value, expiration, opts...)
+}
+
+// MGet 批量获取缓存值，返回的map只包含存在的键
+func (t *Typed[T]) MGet(ctx context.Context, keys ...string) (map[string]T, error) {
+	if len(keys) == 0 {
+		return map[string]T{}, nil
+	}
+
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = t.Key(key)
+	}
+
+	found := make(map[string]T, len(keys))
+	if err := t.cache.MGet(ctx, fullKeys, &found); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]T, len(found))
+	for i, key := range keys {
+		if value, ok := found[fullKeys[i]]; ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// Delete 删除缓存值
+func (t *Typed[T]) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = t.Key(key)
+	}
+	return t.cache.MDel(ctx, fullKeys...)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// TestKeyBuilder 测试缓存键构建
+func TestKeyBuilder(t *testing.T) {
+	kb := NewKeyBuilder("iam").Namespace("user").Version(2)
+
+	if got := kb.Key("42"); got != "iam:user:v2:42" {
+		t.Errorf("Expected key 'iam:user:v2:42', got '%s'", got)
+	}
+	if got := kb.Key("42", "profile"); got != "iam:user:v2:42:profile" {
+		t.Errorf("Expected key 'iam:user:v2:42:profile', got '%s'", got)
+	}
+	if got := kb.Prefix(); got != "iam:user:v2" {
+		t.Errorf("Expected prefix 'iam:user:v2', got '%s'", got)
+	}
+	if got := NewKeyBuilder("").Key("42"); got != "42" {
+		t.Errorf("Expected key '42' without namespace, got '%s'", got)
+	}
+}
+
+// TestTypedNotFound 测试键不存在时的行为
+// 验证Typed返回found=false，底层实现返回ErrNotFound
+func TestTypedNotFound(t *testing.T) {
+	ctx := context.Background()
+	noop := NewNoOpCache()
+	typed := NewTyped[string](noop, NewKeyBuilder("test"))
+
+	value, found, err := typed.Get(ctx, "missing")
+	if err != nil {
+		t.Fatalf("Get returned unexpected error: %v", err)
+	}
+	if found || value != "" {
+		t.Errorf("Expected not found with zero value, got found=%v value=%q", found, value)
+	}
+
+	values, err := typed.MGet(ctx, "a", "b")
+	if err != nil {
+		t.Fatalf("MGet returned unexpected error: %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("Expected empty result, got %v", values)
+	}
+
+	if _, err := noop.GetString(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound from NoOpCache, got %v", err)
+	}
+}
+
+// TestTypedProtobuf 测试protobuf编解码器下Typed读写消息指针类型
+func TestTypedProtobuf(t *testing.T) {
+	server := miniredis.RunT(t)
+	c, err := NewRedisCache(&CacheConfig{Addr: server.Addr(), Codec: CodecProtobuf})
+	if err != nil {
+		t.Fatalf("Failed to create redis cache: %v", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+	typed := NewTyped[*wrapperspb.StringValue](c, nil)
+
+	if err := typed.Set(ctx, "k", wrapperspb.String("hello"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	value, found, err := typed.Get(ctx, "k")
+	if err != nil || !found || value.GetValue() != "hello" {
+		t.Fatalf("Expected hello, got %v (found=%v, err=%v)", value, found, err)
+	}
+
+	values, err := typed.MGet(ctx, "k", "missing")
+	if err != nil || len(values) != 1 || values["k"].GetValue() != "hello" {
+		t.Errorf("Expected one message from MGet, got %v (err=%v)", values, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/vera-byte/vgo-kit/ratelimit"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -19,6 +20,7 @@ import (
 type ClientManager struct {
 	connections map[string]*grpc.ClientConn
 	configs     map[string]ClientConfig
+	dialOptions map[string][]grpc.DialOption
 	logger      *zap.Logger
 	mu          sync.RWMutex
 }
@@ -34,6 +36,7 @@ func NewClientManager(logger *zap.Logger) *ClientManager {
 	return &ClientManager{
 		connections: make(map[string]*grpc.ClientConn),
 		configs:     make(map[string]ClientConfig),
+		dialOptions: make(map[string][]grpc.DialOption),
 		logger:      logger,
 	}
 }
@@ -41,11 +44,13 @@ func NewClientManager(logger *zap.Logger) *ClientManager {
 // AddClient 添加客户端配置
 // name: 客户端名称
 // config: 客户端配置
-func (cm *ClientManager) AddClient(name string, config ClientConfig) {
+// opts: 额外的连接选项，如自定义拦截器
+func (cm *ClientManager) AddClient(name string, config ClientConfig, opts ...grpc.DialOption) {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
 	cm.configs[name] = config
+	cm.dialOptions[name] = opts
 	cm.logger.Info("added gRPC client config", 
 		zap.String("name", name),
 		zap.String("target", config.Target),
@@ -59,6 +64,7 @@ func (cm *ClientManager) GetClient(name string) (*grpc.ClientConn, error) {
 	cm.mu.RLock()
 	conn, exists := cm.connections[name]
 	config, configExists := cm.configs[name]
+	dialOptions := cm.dialOptions[name]
 	cm.mu.RUnlock()
 
 	if !configExists {
@@ -77,7 +83,7 @@ func (cm *ClientManager) GetClient(name string) (*grpc.ClientConn, error) {
 	}
 
 	// 创建新连接
-	conn, err := cm.createConnection(config)
+	conn, err := cm.createConnection(config, dialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection for %s: %w", name, err)
 	}
@@ -96,8 +102,9 @@ func (cm *ClientManager) GetClient(name string) (*grpc.ClientConn, error) {
 
 // createConnection 创建 gRPC 连接
 // config: 客户端配置
+// extra: 额外的连接选项
 // 返回: gRPC 客户端连接和错误信息
-func (cm *ClientManager) createConnection(config ClientConfig) (*grpc.ClientConn, error) {
+func (cm *ClientManager) createConnection(config ClientConfig, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectionTimeout)
 	defer cancel()
 
@@ -126,6 +133,16 @@ func (cm *ClientManager) createConnection(config ClientConfig) (*grpc.ClientConn
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	// 配置客户端限流
+	if config.RateLimit != nil {
+		limiter := ratelimit.NewClientLimiter(config.RateLimit, nil)
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(ratelimit.UnaryClientInterceptor(limiter)),
+			grpc.WithChainStreamInterceptor(ratelimit.StreamClientInterceptor(limiter)),
+		)
+	}
+	opts = append(opts, extra...)
+
 	// 创建连接
 	conn, err := grpc.DialContext(ctx, config.Target, opts...)
 	if err != nil {
@@ -176,6 +193,7 @@ func (cm *ClientManager) CloseAll() error {
 	// 清空连接映射
 	cm.connections = make(map[string]*grpc.ClientConn)
 	cm.configs = make(map[string]ClientConfig)
+	cm.dialOptions = make(map[string][]grpc.DialOption)
 
 	return lastErr
 }
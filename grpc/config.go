@@ -2,6 +2,8 @@ package grpc
 
 import (
 	"time"
+
+	"github.com/vera-byte/vgo-kit/ratelimit"
 )
 
 // Config gRPC 配置结构
@@ -46,6 +48,8 @@ type ClientConfig struct {
 	TLS *TLSConfig `mapstructure:"tls" yaml:"tls"`
 	// Retry 重试配置
 	Retry RetryConfig `mapstructure:"retry" yaml:"retry"`
+	// RateLimit 客户端限流配置，为空时不限流
+	RateLimit *ratelimit.ClientLimitConfig `mapstructure:"rate_limit" yaml:"rate_limit"`
 }
 
 // KeepAliveConfig 保活配置
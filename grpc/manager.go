@@ -122,8 +122,9 @@ func (m *Manager) GetClient(name string) (*grpc.ClientConn, error) {
 // AddClient 添加客户端配置
 // name: 客户端名称
 // config: 客户端配置
-func (m *Manager) AddClient(name string, config ClientConfig) {
-	m.clientManager.AddClient(name, config)
+// opts: 额外的连接选项
+func (m *Manager) AddClient(name string, config ClientConfig, opts ...grpc.DialOption) {
+	m.clientManager.AddClient(name, config, opts...)
 	m.config.Clients[name] = config
 }
 
@@ -0,0 +1,89 @@
+// Package httpx 提供与gRPC拦截器等价的net/http中间件：限流、国际化、指标、请求ID、访问日志和panic恢复
+package httpx
+
+import (
+	"context"
+	"net/http"
+)
+
+// Middleware net/http中间件
+type Middleware func(http.Handler) http.Handler
+
+// Chain 组合多个中间件，第一个中间件位于最外层
+//
+//	handler := httpx.Chain(httpx.Recovery(logger), httpx.RequestID(), httpx.AccessLog(logger))(mux)
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// serveWithContext 使用新的上下文调用next，并将ServeMux匹配到的路由模式带回原请求，供外层中间件读取
+func serveWithContext(next http.Handler, w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	req := r.WithContext(ctx)
+	next.ServeHTTP(w, req)
+	if r.Pattern == "" {
+		r.Pattern = req.Pattern
+	}
+}
+
+// responseRecorder 记录响应状态码和写入的字节数
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// wrapResponseWriter 包装ResponseWriter，已包装时直接返回，多个中间件共享同一个记录
+func wrapResponseWriter(w http.ResponseWriter) *responseRecorder {
+	if recorder, ok := w.(*responseRecorder); ok {
+		return recorder
+	}
+	return &responseRecorder{ResponseWriter: w}
+}
+
+// WriteHeader 记录状态码
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write 记录写入的字节数
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush 支持流式响应
+func (r *responseRecorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 供http.ResponseController访问底层ResponseWriter
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status 响应状态码，未写入时为200
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,40 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/vera-byte/vgo-kit/i18n"
+)
+
+// Language 语言识别中间件
+// 按config.LanguageHeader(默认Accept-Language)、Language、Lang的顺序读取请求头，解析出的语言写入上下文
+// (i18n.GetLanguageFromContext)和Content-Language响应头。
+// 与gRPC拦截器不同，这里不修改共享Translator的当前语言，翻译时应使用TranslateWithLang
+func Language(config *i18n.InterceptorConfig) Middleware {
+	if config == nil {
+		config = i18n.DefaultInterceptorConfig()
+	}
+	header := config.LanguageHeader
+	if header == "" {
+		header = "Accept-Language"
+	}
+	defaultLang := config.DefaultLanguage
+	if defaultLang == "" {
+		defaultLang = i18n.DefaultLanguage
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := defaultLang
+			for _, name := range []string{header, "Language", "Lang"} {
+				if value := r.Header.Get(name); value != "" {
+					lang = i18n.ParseAcceptLanguage(value)
+					break
+				}
+			}
+
+			w.Header().Set("Content-Language", string(lang))
+			serveWithContext(next, w, r, i18n.SetLanguageToContext(r.Context(), lang))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package httpx
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AccessLog 访问日志中间件，5xx响应记录为错误
+func AccessLog(logger *zap.Logger) Middleware {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := wrapResponseWriter(w)
+
+			next.ServeHTTP(recorder, r)
+
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", recorder.Status()),
+				zap.Int64("bytes", recorder.bytes),
+				zap.Duration("duration", time.Since(start)),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("user_agent", r.UserAgent()),
+			}
+			if r.Pattern != "" {
+				fields = append(fields, zap.String("route", r.Pattern))
+			}
+			if requestID := requestIDOf(recorder, r); requestID != "" {
+				fields = append(fields, zap.String("request_id", requestID))
+			}
+
+			if recorder.Status() >= http.StatusInternalServerError {
+				logger.Error("HTTP request failed", fields...)
+			} else {
+				logger.Info("HTTP request completed", fields...)
+			}
+		})
+	}
+}
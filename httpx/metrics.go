@@ -0,0 +1,42 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+)
+
+// unmatchedRoute 未经ServeMux路由的请求在指标中的路由名称
+const unmatchedRoute = "unmatched"
+
+// Metrics 记录HTTP请求数和耗时的中间件
+// 路由标签使用http.ServeMux匹配到的模式(如 "POST /webhooks/{id}")，避免按原始路径产生过多的标签值。
+// 处理器之间的第三方中间件替换请求时无法获取路由模式，此时记录为unmatched。
+// collector未实现 metrics.HTTPMetrics 时只记录5xx错误
+func Metrics(collector metrics.MetricsCollector) Middleware {
+	httpMetrics, _ := collector.(metrics.HTTPMetrics)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := wrapResponseWriter(w)
+
+			next.ServeHTTP(recorder, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := recorder.Status()
+			if httpMetrics != nil {
+				httpMetrics.RecordHTTPRequest(r.Method, route, status, time.Since(start))
+			}
+
+			// 记录错误详情
+			if status >= http.StatusInternalServerError {
+				collector.RecordError("http", strconv.Itoa(status))
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/i18n"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"github.com/vera-byte/vgo-kit/ratelimit"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// httpRecorder 记录HTTP请求指标
+type httpRecorder struct {
+	metrics.MetricsCollector
+	metrics.HTTPMetrics
+	requests []string
+	errors   []string
+}
+
+func (r *httpRecorder) RecordHTTPRequest(method, route string, code int, duration time.Duration) {
+	r.requests = append(r.requests, method+" "+route+" "+http.StatusText(code))
+}
+
+func (r *httpRecorder) RecordError(errorType, errorCode string) {
+	r.errors = append(r.errors, errorType+":"+errorCode)
+}
+
+// TestChain 测试中间件按顺序执行
+func TestChain(t *testing.T) {
+	var order []string
+	middleware := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := Chain(middleware("a"), middleware("b"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if strings.Join(order, ",") != "a,b,handler" {
+		t.Errorf("Expected a,b,handler, got %v", order)
+	}
+}
+
+// TestRequestID 测试沿用合法的请求ID并替换非法值
+func TestRequestID(t *testing.T) {
+	var got string
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		incoming string
+		keep     bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"bad id\nwith newline", false},
+		{strings.Repeat("a", 200), false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.incoming != "" {
+			req.Header.Set(HeaderRequestID, tt.incoming)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got == "" || rec.Header().Get(HeaderRequestID) != got {
+			t.Errorf("Expected request ID in context and response header, got %q and %q", got, rec.Header().Get(HeaderRequestID))
+		}
+		if (got == tt.incoming) != tt.keep {
+			t.Errorf("Incoming %q: expected keep=%v, got %q", tt.incoming, tt.keep, got)
+		}
+	}
+}
+
+// TestRecovery 测试panic返回500并记录日志
+func TestRecovery(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	handler := Chain(Recovery(zap.New(core)), RequestID())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500, got %d", rec.Code)
+	}
+	entries := logs.FilterMessage("HTTP panic recovered").All()
+	if len(entries) != 1 || entries[0].ContextMap()["request_id"] == "" {
+		t.Errorf("Expected one panic log with request ID, got %v", entries)
+	}
+}
+
+// TestAccessLogAndMetrics 测试访问日志和指标使用ServeMux的路由模式
+func TestAccessLogAndMetrics(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	recorder := &httpRecorder{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("item"))
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	handler := Chain(Metrics(recorder), AccessLog(zap.New(core)), RequestID(), Language(nil))(mux)
+
+	for _, path := range []string{"/items/1", "/items/2", "/fail", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []string{
+		"GET GET /items/{id} OK",
+		"GET GET /items/{id} OK",
+		"GET /fail Bad Gateway",
+		"GET unmatched Not Found",
+	}
+	if strings.Join(recorder.requests, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, recorder.requests)
+	}
+	if len(recorder.errors) != 1 || recorder.errors[0] != "http:502" {
+		t.Errorf("Expected one 502 error, got %v", recorder.errors)
+	}
+
+	completed := logs.FilterMessage("HTTP request completed").All()
+	if len(completed) != 3 {
+		t.Fatalf("Expected 3 completed logs, got %d", len(completed))
+	}
+	fields := completed[0].ContextMap()
+	if fields["route"] != "GET /items/{id}" || fields["status"] != int64(http.StatusOK) || fields["request_id"] == nil {
+		t.Errorf("Unexpected access log fields: %v", fields)
+	}
+	if logs.FilterMessage("HTTP request failed").Len() != 1 {
+		t.Error("Expected 5xx response to be logged as failed")
+	}
+}
+
+// TestLanguage 测试从请求头识别语言
+func TestLanguage(t *testing.T) {
+	var got i18n.SupportedLanguage
+	handler := Language(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = i18n.GetLanguageFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got != i18n.LanguageChinese || rec.Header().Get("Content-Language") != string(i18n.LanguageChinese) {
+		t.Errorf("Expected zh, got %s (Content-Language %s)", got, rec.Header().Get("Content-Language"))
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != i18n.DefaultLanguage {
+		t.Errorf("Expected default language, got %s", got)
+	}
+}
+
+// TestRateLimit 测试按客户端IP限流和响应头
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(&ratelimit.InterceptorConfig{
+		RateLimiter: ratelimit.NewMemoryRateLimiter(1, time.Minute),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("203.0.113.7:5000")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Ratelimit-Limit") != "1" || rec.Header().Get("X-Ratelimit-Remaining") != "0" {
+		t.Errorf("Expected allowed request with headers, got %d %v", rec.Code, rec.Header())
+	}
+	rec = request("203.0.113.7:5001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := request("198.51.100.1:5000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected other client to pass, got %d", rec.Code)
+	}
+}
+
+// failingLimiter 总是出错的限流器
+type failingLimiter struct{ ratelimit.RateLimiter }
+
+func (failingLimiter) Decide(ctx context.Context, key string, n int) (*ratelimit.Decision, error) {
+	return nil, errors.New("connection refused")
+}
+
+// TestRateLimitFailClosed 测试限流器出错时按失败策略返回503
+func TestRateLimitFailClosed(t *testing.T) {
+	handler := RateLimit(&ratelimit.InterceptorConfig{
+		RateLimiter:   failingLimiter{},
+		FailurePolicy: ratelimit.FailClosed,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+}
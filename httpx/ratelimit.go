@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimit 限流中间件，复用ratelimit.InterceptorConfig(限流器、key函数、规则集、失败策略、影子模式和指标)
+// 为了复用gRPC的key函数和规则，请求被转换为gRPC形式：请求头作为元数据，RemoteAddr作为对端地址，
+// 请求路径作为方法名(规则的methods可配置为 /webhooks/* 等路径glob)。
+// 被限流时返回429并设置Retry-After，失败策略为closed且限流器出错时返回503
+func RateLimit(config *ratelimit.InterceptorConfig) Middleware {
+	check := ratelimit.CheckFunc(config)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, info := grpcRequest(r)
+			if config.SkipFunc(ctx, info) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			decision, err := check(ctx, info)
+			if decision != nil {
+				setRateLimitHeaders(w.Header(), decision)
+			}
+			if err != nil {
+				code := http.StatusServiceUnavailable
+				if status.Code(err) == codes.ResourceExhausted {
+					code = http.StatusTooManyRequests
+				}
+				http.Error(w, status.Convert(err).Message(), code)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// grpcRequest 将HTTP请求转换为gRPC key函数使用的上下文和方法信息
+func grpcRequest(r *http.Request) (context.Context, *grpc.UnaryServerInfo) {
+	md := make(metadata.MD, len(r.Header))
+	for name, values := range r.Header {
+		md.Append(name, values...)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: net.TCPAddrFromAddrPort(addr)})
+	}
+	return ctx, &grpc.UnaryServerInfo{FullMethod: r.URL.Path}
+}
+
+// setRateLimitHeaders 设置限流响应头，与gRPC拦截器的响应元数据一致
+func setRateLimitHeaders(header http.Header, decision *ratelimit.Decision) {
+	if decision.Limit <= 0 {
+		return
+	}
+	header.Set(ratelimit.HeaderRateLimitLimit, strconv.Itoa(decision.Limit))
+	header.Set(ratelimit.HeaderRateLimitRemaining, strconv.Itoa(decision.Remaining))
+	header.Set(ratelimit.HeaderRateLimitReset, strconv.FormatInt(ceilSeconds(decision.ResetAfter), 10))
+	if !decision.Allowed && decision.RetryAfter > 0 {
+		header.Set(ratelimit.HeaderRetryAfter, strconv.FormatInt(ceilSeconds(decision.RetryAfter), 10))
+	}
+}
+
+// ceilSeconds 向上取整的秒数
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
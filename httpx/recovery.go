@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Recovery panic恢复中间件，记录panic和调用栈并返回500
+// http.ErrAbortHandler会被重新抛出，由net/http中止响应
+func Recovery(logger *zap.Logger) Middleware {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := wrapResponseWriter(w)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				logger.Error("HTTP panic recovered",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("request_id", requestIDOf(recorder, r)),
+					zap.Any("panic", rec),
+					zap.Stack("stack"),
+				)
+				// 已开始写入响应时无法再修改状态码
+				if !recorder.wroteHeader {
+					http.Error(recorder, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+
+	vgologger "github.com/vera-byte/vgo-kit/logger"
+)
+
+// HeaderRequestID 请求ID头
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength 接受的客户端请求ID最大长度
+const maxRequestIDLength = 128
+
+// requestIDContextKey 上下文中请求ID的键
+type requestIDContextKey struct{}
+
+// RequestID 请求ID中间件
+// 沿用客户端或上游代理传入的合法请求ID，否则生成UUID；请求ID写入上下文和响应头
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(HeaderRequestID)
+			if !validRequestID(requestID) {
+				requestID = vgologger.GenerateRequestID()
+			}
+			w.Header().Set(HeaderRequestID, requestID)
+			serveWithContext(next, w, r, context.WithValue(r.Context(), requestIDContextKey{}, requestID))
+		})
+	}
+}
+
+// RequestIDFromContext 获取RequestID中间件写入的请求ID，不存在时为空
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// requestIDOf 获取请求ID，外层中间件读不到内层写入的上下文时使用响应头中的请求ID
+func requestIDOf(w http.ResponseWriter, r *http.Request) string {
+	if requestID := RequestIDFromContext(r.Context()); requestID != "" {
+		return requestID
+	}
+	return w.Header().Get(HeaderRequestID)
+}
+
+// validRequestID 只接受长度有限的字母、数字和 -_.: 字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+// Package messaging 提供基于Redis的轻量级消息组件：类型化的发布/订阅，以及基于Streams消费组的可靠消费
+package messaging
+
+import (
+	"github.com/vera-byte/vgo-kit/cache"
+	"go.uber.org/zap"
+)
+
+// payloadField 消息在Stream条目中的字段名
+const payloadField = "payload"
+
+// Option 消息组件选项
+type Option func(*options)
+
+// options 消息组件选项集合
+type options struct {
+	codec  cache.Codec
+	logger *zap.Logger
+}
+
+// WithCodec 设置消息编解码器，默认JSON
+func WithCodec(codec cache.Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithLogger 设置日志器，用于记录解码失败、处理失败和死信
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// applyOptions 合并选项并补全默认值
+func applyOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.codec == nil {
+		o.codec, _ = cache.NewCodec(cache.CodecJSON)
+	}
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	return o
+}
@@ -0,0 +1,140 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisClient 连接本地Redis，不可用时跳过测试，可通过 VGO_TEST_REDIS_ADDR 覆盖地址
+func newTestRedisClient(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	addr := os.Getenv("VGO_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, DialTimeout: 500 * time.Millisecond})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+type event struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for condition")
+}
+
+// TestTopicPublishSubscribe 测试类型化发布/订阅
+func TestTopicPublishSubscribe(t *testing.T) {
+	client := newTestRedisClient(t)
+	ctx := context.Background()
+	topic := NewTopic[event](client, "test:topic:"+uuid.NewString())
+
+	received := make(chan event, 1)
+	sub, err := topic.Subscribe(ctx, func(ctx context.Context, e event) error {
+		received <- e
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Close()
+
+	if _, err := topic.Publish(ctx, event{ID: 1, Name: "created"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case e := <-received:
+		if e.ID != 1 || e.Name != "created" {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+}
+
+// TestStreamConsumerRetriesAndDeadLetters 测试消费、重试认领与死信
+func TestStreamConsumerRetriesAndDeadLetters(t *testing.T) {
+	client := newTestRedisClient(t)
+	ctx := context.Background()
+	name := "test:stream:" + uuid.NewString()
+	defer client.Del(ctx, name, name+":dead")
+
+	stream, err := NewStream[event](client, &StreamConfig{
+		Stream:        name,
+		Group:         "workers",
+		Block:         50 * time.Millisecond,
+		ClaimInterval: 20 * time.Millisecond,
+		MinIdle:       10 * time.Millisecond,
+		MaxDeliveries: 3,
+	})
+	if err != nil {
+		t.Fatalf("NewStream failed: %v", err)
+	}
+
+	var handled, failures int32
+	consumer := stream.NewConsumer(func(ctx context.Context, m *Message[event]) error {
+		if m.Payload.Name == "poison" {
+			atomic.AddInt32(&failures, 1)
+			return errors.New("cannot handle")
+		}
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+	if err := consumer.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := consumer.Start(ctx); !errors.Is(err, ErrConsumerStarted) {
+		t.Errorf("Expected ErrConsumerStarted, got %v", err)
+	}
+
+	stream.Publish(ctx, event{ID: 1, Name: "ok"})
+	stream.Publish(ctx, event{ID: 2, Name: "poison"})
+
+	waitFor(t, 3*time.Second, func() bool {
+		return client.XLen(ctx, name+":dead").Val() == 1
+	})
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := consumer.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	if handled != 1 {
+		t.Errorf("Expected 1 handled message, got %d", handled)
+	}
+	if failures != 3 {
+		t.Errorf("Expected 3 delivery attempts, got %d", failures)
+	}
+	if pending := client.XPending(ctx, name, "workers").Val(); pending.Count != 0 {
+		t.Errorf("Expected no pending messages, got %d", pending.Count)
+	}
+
+	dead := client.XRange(ctx, name+":dead", "-", "+").Val()
+	if dead[0].Values["deliveries"] != "3" || dead[0].Values["reason"] != "max deliveries exceeded" {
+		t.Errorf("Unexpected dead-letter entry %v", dead[0].Values)
+	}
+}
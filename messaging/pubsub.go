@@ -0,0 +1,75 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Topic 类型化的Redis发布/订阅频道，消息不持久化，订阅者离线期间的消息会丢失
+type Topic[T any] struct {
+	client  redis.UniversalClient
+	channel string
+	options options
+}
+
+// NewTopic 创建发布/订阅频道
+func NewTopic[T any](client redis.UniversalClient, channel string, opts ...Option) *Topic[T] {
+	return &Topic[T]{
+		client:  client,
+		channel: channel,
+		options: applyOptions(opts),
+	}
+}
+
+// Publish 发布消息，返回收到消息的订阅者数量
+func (t *Topic[T]) Publish(ctx context.Context, message T) (int64, error) {
+	data, err := t.options.codec.Marshal(message)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return t.client.Publish(ctx, t.channel, data).Result()
+}
+
+// Subscribe 订阅频道，每条消息在独立的后台goroutine中按顺序调用handler，处理失败只记录日志
+func (t *Topic[T]) Subscribe(ctx context.Context, handler func(ctx context.Context, message T) error) (*Subscription, error) {
+	pubsub := t.client.Subscribe(ctx, t.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe channel %s: %w", t.channel, err)
+	}
+
+	s := &Subscription{pubsub: pubsub, done: make(chan struct{})}
+	handlerCtx := context.WithoutCancel(ctx)
+	go func() {
+		defer close(s.done)
+		for msg := range pubsub.Channel() {
+			var message T
+			if err := t.options.codec.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				t.options.logger.Error("Failed to decode message",
+					zap.String("channel", t.channel), zap.Error(err))
+				continue
+			}
+			if err := handler(handlerCtx, message); err != nil {
+				t.options.logger.Error("Failed to handle message",
+					zap.String("channel", t.channel), zap.Error(err))
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Subscription 频道订阅
+type Subscription struct {
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// Close 取消订阅，并等待正在处理的消息完成
+func (s *Subscription) Close() error {
+	err := s.pubsub.Close()
+	<-s.done
+	return err
+}
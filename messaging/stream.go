@@ -0,0 +1,370 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrConsumerStarted 消费者已启动
+var ErrConsumerStarted = errors.New("messaging: consumer already started")
+
+// StreamConfig Redis Streams配置
+type StreamConfig struct {
+	Stream           string        `mapstructure:"stream"`             // Stream键名
+	Group            string        `mapstructure:"group"`              // 消费组名称
+	Consumer         string        `mapstructure:"consumer"`           // 消费者名称，默认为主机名加随机后缀
+	MaxLen           int64         `mapstructure:"max_len"`            // Stream近似最大长度，0表示不裁剪
+	BatchSize        int64         `mapstructure:"batch_size"`         // 每次读取的消息数量
+	Block            time.Duration `mapstructure:"block"`              // 读取阻塞时间
+	ClaimInterval    time.Duration `mapstructure:"claim_interval"`     // 认领超时未确认消息的间隔
+	MinIdle          time.Duration `mapstructure:"min_idle"`           // 消息未确认超过该时间后可被其他消费者认领
+	MaxDeliveries    int64         `mapstructure:"max_deliveries"`     // 最大投递次数，超过后转入死信Stream
+	DeadLetterStream string        `mapstructure:"dead_letter_stream"` // 死信Stream，默认为 Stream + ":dead"
+}
+
+// DefaultStreamConfig 默认Streams配置
+func DefaultStreamConfig() *StreamConfig {
+	return &StreamConfig{
+		BatchSize:     10,
+		Block:         5 * time.Second,
+		ClaimInterval: 30 * time.Second,
+		MinIdle:       time.Minute,
+		MaxDeliveries: 5,
+	}
+}
+
+// Message Stream消息
+type Message[T any] struct {
+	ID         string // 消息ID
+	Payload    T      // 消息内容
+	Deliveries int64  // 投递次数，首次投递为1
+}
+
+// Handler 消息处理函数，返回nil时确认消息；返回错误时消息保持未确认，超过MinIdle后重新投递
+type Handler[T any] func(ctx context.Context, message *Message[T]) error
+
+// Stream 类型化的Redis Stream
+type Stream[T any] struct {
+	client  redis.UniversalClient
+	config  StreamConfig
+	options options
+}
+
+// NewStream 创建Stream，config中的Stream和Group为必填项
+func NewStream[T any](client redis.UniversalClient, config *StreamConfig, opts ...Option) (*Stream[T], error) {
+	if config == nil || config.Stream == "" {
+		return nil, fmt.Errorf("stream name is required")
+	}
+	c := *config
+	defaults := DefaultStreamConfig()
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.Block <= 0 {
+		c.Block = defaults.Block
+	}
+	if c.ClaimInterval <= 0 {
+		c.ClaimInterval = defaults.ClaimInterval
+	}
+	if c.MinIdle <= 0 {
+		c.MinIdle = defaults.MinIdle
+	}
+	if c.MaxDeliveries <= 0 {
+		c.MaxDeliveries = defaults.MaxDeliveries
+	}
+	if c.DeadLetterStream == "" {
+		c.DeadLetterStream = c.Stream + ":dead"
+	}
+	if c.Consumer == "" {
+		hostname, _ := os.Hostname()
+		c.Consumer = hostname + "-" + uuid.NewString()[:8]
+	}
+
+	return &Stream[T]{
+		client:  client,
+		config:  c,
+		options: applyOptions(opts),
+	}, nil
+}
+
+// Publish 发布消息，返回消息ID
+func (s *Stream[T]) Publish(ctx context.Context, message T) (string, error) {
+	data, err := s.options.codec.Marshal(message)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: s.config.Stream,
+		Values: map[string]interface{}{payloadField: data},
+	}
+	if s.config.MaxLen > 0 {
+		args.MaxLen = s.config.MaxLen
+		args.Approx = true
+	}
+	return s.client.XAdd(ctx, args).Result()
+}
+
+// NewConsumer 创建消费者，需要调用Start开始消费
+func (s *Stream[T]) NewConsumer(handler Handler[T]) *Consumer[T] {
+	return &Consumer[T]{stream: s, handler: handler}
+}
+
+// Consumer 消费组中的消费者
+type Consumer[T any] struct {
+	stream  *Stream[T]
+	handler Handler[T]
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Start 创建消费组（不存在时）并在后台开始消费新消息和认领超时消息
+// ctx只用于创建消费组，传给handler的上下文不会随ctx取消
+func (c *Consumer[T]) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.started {
+		return ErrConsumerStarted
+	}
+
+	config := c.stream.config
+	if config.Group == "" {
+		return fmt.Errorf("consumer group is required")
+	}
+	err := c.stream.client.XGroupCreateMkStream(ctx, config.Stream, config.Group, "0").Err()
+	if err != nil && !redis.HasErrorPrefix(err, "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.cancel = cancel
+	c.started = true
+
+	c.wg.Add(2)
+	go c.readLoop(runCtx)
+	go c.claimLoop(runCtx)
+	return nil
+}
+
+// Stop 停止读取新消息，并等待正在处理的消息完成，ctx结束时不再等待
+func (c *Consumer[T]) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return nil
+	}
+	c.started = false
+	c.cancel()
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readLoop 读取新消息
+func (c *Consumer[T]) readLoop(ctx context.Context) {
+	defer c.wg.Done()
+
+	config := c.stream.config
+	for ctx.Err() == nil {
+		streams, err := c.stream.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    config.Group,
+			Consumer: config.Consumer,
+			Streams:  []string{config.Stream, ">"},
+			Count:    config.BatchSize,
+			Block:    config.Block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+			c.stream.options.logger.Error("Failed to read stream",
+				zap.String("stream", config.Stream), zap.Error(err))
+			c.sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				// 停止后剩余消息保持未确认，由其他消费者认领
+				if ctx.Err() != nil {
+					return
+				}
+				c.process(message, 1)
+			}
+		}
+	}
+}
+
+// claimLoop 定期认领超时未确认的消息，超过最大投递次数的消息转入死信Stream
+func (c *Consumer[T]) claimLoop(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.stream.config.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.claim(ctx); err != nil && ctx.Err() == nil {
+				c.stream.options.logger.Error("Failed to claim pending messages",
+					zap.String("stream", c.stream.config.Stream), zap.Error(err))
+			}
+		}
+	}
+}
+
+// claim 认领一批超时未确认的消息
+func (c *Consumer[T]) claim(ctx context.Context) error {
+	config := c.stream.config
+	pending, err := c.stream.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: config.Stream,
+		Group:  config.Group,
+		Idle:   config.MinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  config.BatchSize,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	ids := make([]string, len(pending))
+	deliveries := make(map[string]int64, len(pending))
+	for i, entry := range pending {
+		ids[i] = entry.ID
+		deliveries[entry.ID] = entry.RetryCount
+	}
+
+	messages, err := c.stream.client.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   config.Stream,
+		Group:    config.Group,
+		Consumer: config.Consumer,
+		MinIdle:  config.MinIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			return nil
+		}
+		count := deliveries[message.ID]
+		if count >= config.MaxDeliveries {
+			c.deadLetter(message, count, "max deliveries exceeded")
+			continue
+		}
+		c.process(message, count+1)
+	}
+	return nil
+}
+
+// process 解码并处理消息，成功后确认；无法解码的消息直接转入死信Stream
+func (c *Consumer[T]) process(message redis.XMessage, deliveries int64) {
+	logger := c.stream.options.logger.With(
+		zap.String("stream", c.stream.config.Stream),
+		zap.String("message_id", message.ID),
+	)
+
+	payload, ok := message.Values[payloadField].(string)
+	if !ok {
+		c.deadLetter(message, deliveries, "missing payload")
+		return
+	}
+	msg := &Message[T]{ID: message.ID, Deliveries: deliveries}
+	if err := c.stream.options.codec.Unmarshal([]byte(payload), &msg.Payload); err != nil {
+		c.deadLetter(message, deliveries, err.Error())
+		return
+	}
+
+	if err := c.handle(msg); err != nil {
+		logger.Warn("Failed to handle message", zap.Int64("deliveries", deliveries), zap.Error(err))
+		return
+	}
+	c.ack(message.ID)
+}
+
+// handle 调用handler并将panic转换为错误
+func (c *Consumer[T]) handle(message *Message[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.handler(context.Background(), message)
+}
+
+// ack 确认消息
+func (c *Consumer[T]) ack(id string) {
+	config := c.stream.config
+	if err := c.stream.client.XAck(context.Background(), config.Stream, config.Group, id).Err(); err != nil {
+		c.stream.options.logger.Error("Failed to ack message",
+			zap.String("stream", config.Stream), zap.String("message_id", id), zap.Error(err))
+	}
+}
+
+// deadLetter 将消息写入死信Stream并确认原消息
+func (c *Consumer[T]) deadLetter(message redis.XMessage, deliveries int64, reason string) {
+	config := c.stream.config
+	values := map[string]interface{}{
+		"source_id":  message.ID,
+		"deliveries": strconv.FormatInt(deliveries, 10),
+		"reason":     reason,
+	}
+	if payload, ok := message.Values[payloadField]; ok {
+		values[payloadField] = payload
+	}
+
+	err := c.stream.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: config.DeadLetterStream,
+		Values: values,
+	}).Err()
+	if err != nil {
+		c.stream.options.logger.Error("Failed to dead-letter message",
+			zap.String("stream", config.Stream), zap.String("message_id", message.ID), zap.Error(err))
+		return
+	}
+
+	c.stream.options.logger.Warn("Message moved to dead-letter stream",
+		zap.String("stream", config.Stream),
+		zap.String("message_id", message.ID),
+		zap.Int64("deliveries", deliveries),
+		zap.String("reason", reason))
+	c.ack(message.ID)
+}
+
+// sleep 等待指定时间或ctx结束
+func (c *Consumer[T]) sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -3,6 +3,8 @@ package metrics
 import (
 	"context"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,16 +40,70 @@ type MetricsCollector interface {
 	GetHTTPHandler() http.Handler
 }
 
+// 以下为可选的指标接口，各组件通过类型断言检测 MetricsCollector 是否实现，未实现时不记录对应指标。
+// DefaultMetrics 实现了全部可选接口。
+
+// HTTPMetrics HTTP相关指标
+type HTTPMetrics interface {
+	RecordHTTPRequest(method, route string, code int, duration time.Duration)
+}
+
+// CacheMetrics 缓存相关指标
+type CacheMetrics interface {
+	RecordCacheAccess(tier string, hit bool)
+	RecordCacheCommand(command string, duration time.Duration, err error)
+	UpdateCachePoolStats(pool string, hits, misses, timeouts, idle, total int)
+	UpdateCacheCircuitState(name, state string)
+}
+
+// RateLimitMetrics 限流相关指标
+type RateLimitMetrics interface {
+	UpdateAdaptiveLimit(limiter string, limit int)
+	RecordRateLimitError(policy string)
+	RecordRateLimitDecision(rule, outcome string)
+	RecordConcurrencyLeaseLost(reacquired bool)
+}
+
+// DefaultMetrics 实现的接口
+var (
+	_ MetricsCollector = (*DefaultMetrics)(nil)
+	_ HTTPMetrics      = (*DefaultMetrics)(nil)
+	_ CacheMetrics     = (*DefaultMetrics)(nil)
+	_ RateLimitMetrics = (*DefaultMetrics)(nil)
+)
+
 // DefaultMetrics 默认指标收集器实现
 type DefaultMetrics struct {
 	// gRPC 请求计数器
 	grpcRequestsTotal *prometheus.CounterVec
 	// gRPC 请求持续时间
 	grpcRequestDuration *prometheus.HistogramVec
+	// HTTP 请求计数器
+	httpRequestsTotal *prometheus.CounterVec
+	// HTTP 请求持续时间
+	httpRequestDuration *prometheus.HistogramVec
 	// 数据库连接池指标
 	dbConnectionsActive prometheus.Gauge
 	dbConnectionsIdle   prometheus.Gauge
 	dbConnectionsTotal  prometheus.Gauge
+	// 缓存访问指标
+	cacheRequestsTotal *prometheus.CounterVec
+	// 缓存命令持续时间
+	cacheCommandDuration *prometheus.HistogramVec
+	// 缓存连接池指标
+	cachePoolCounters   *cachePoolCollector
+	cachePoolIdleConns  *prometheus.GaugeVec
+	cachePoolTotalConns *prometheus.GaugeVec
+	// 缓存熔断器状态
+	cacheCircuitState *prometheus.GaugeVec
+	// 自适应限流器当前并发上限
+	adaptiveLimit *prometheus.GaugeVec
+	// 限流器错误计数
+	rateLimitErrors *prometheus.CounterVec
+	// 限流决策计数
+	rateLimitDecisions *prometheus.CounterVec
+	// 并发租约丢失计数
+	concurrencyLeasesLost *prometheus.CounterVec
 	// 业务指标
 	businessMetrics *prometheus.CounterVec
 	// 错误指标
@@ -81,6 +137,23 @@ func NewMetrics(namespace string) *DefaultMetrics {
 			},
 			[]string{"method"},
 		),
+		httpRequestsTotal: promauto.With(registry).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "http_requests_total",
+				Help:      "Total number of HTTP requests",
+			},
+			[]string{"method", "route", "code"},
+		),
+		httpRequestDuration: promauto.With(registry).NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "http_request_duration_seconds",
+				Help:      "Duration of HTTP requests in seconds",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"method", "route"},
+		),
 		dbConnectionsActive: promauto.With(registry).NewGauge(
 			prometheus.GaugeOpts{
 				Namespace: namespace,
@@ -102,6 +175,80 @@ func NewMetrics(namespace string) *DefaultMetrics {
 				Help:      "Total number of database connections",
 			},
 		),
+		cacheRequestsTotal: promauto.With(registry).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "cache_requests_total",
+				Help:      "Total number of cache lookups by tier and result",
+			},
+			[]string{"tier", "result"},
+		),
+		cacheCommandDuration: promauto.With(registry).NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Name:      "cache_command_duration_seconds",
+				Help:      "Duration of cache commands in seconds",
+				Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+			},
+			[]string{"command", "status"},
+		),
+		cachePoolCounters: newCachePoolCollector(namespace),
+		cachePoolIdleConns: promauto.With(registry).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "cache_pool_connections_idle",
+				Help:      "Number of idle connections in the cache pool",
+			},
+			[]string{"pool"},
+		),
+		cachePoolTotalConns: promauto.With(registry).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "cache_pool_connections_total",
+				Help:      "Total number of connections in the cache pool",
+			},
+			[]string{"pool"},
+		),
+		cacheCircuitState: promauto.With(registry).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "cache_circuit_state",
+				Help:      "Current cache circuit breaker state, 1 for the active state",
+			},
+			[]string{"name", "state"},
+		),
+		adaptiveLimit: promauto.With(registry).NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "ratelimit_adaptive_limit",
+				Help:      "Current concurrency limit of adaptive rate limiters",
+			},
+			[]string{"limiter"},
+		),
+		rateLimitErrors: promauto.With(registry).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "ratelimit_errors_total",
+				Help:      "Total number of rate limiter backend errors by failure policy",
+			},
+			[]string{"policy"},
+		),
+		rateLimitDecisions: promauto.With(registry).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "ratelimit_decisions_total",
+				Help:      "Total number of rate limit decisions by rule and outcome",
+			},
+			[]string{"rule", "outcome"},
+		),
+		concurrencyLeasesLost: promauto.With(registry).NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "ratelimit_concurrency_leases_lost_total",
+				Help:      "Total number of concurrency leases reaped while the request was still running",
+			},
+			[]string{"reacquired"},
+		),
 		businessMetrics: promauto.With(registry).NewCounterVec(
 			prometheus.CounterOpts{
 				Namespace: namespace,
@@ -128,6 +275,9 @@ func NewMetrics(namespace string) *DefaultMetrics {
 		),
 	}
 
+	// 连接池累计统计以计数器导出
+	registry.MustRegister(m.cachePoolCounters)
+
 	// 注册默认的Go运行时指标
 	registry.MustRegister(collectors.NewGoCollector())
 	registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
@@ -149,6 +299,12 @@ func (m *DefaultMetrics) RecordGRPCDuration(method string, duration time.Duratio
 	m.grpcRequestDuration.WithLabelValues(method).Observe(duration.Seconds())
 }
 
+// RecordHTTPRequest 记录HTTP请求，route应为路由模式而不是原始路径，避免标签基数过高
+func (m *DefaultMetrics) RecordHTTPRequest(method, route string, code int, duration time.Duration) {
+	m.httpRequestsTotal.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	m.httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
 // UpdateDBConnections 更新数据库连接指标
 func (m *DefaultMetrics) UpdateDBConnections(active, idle, total int) {
 	m.dbConnectionsActive.Set(float64(active))
@@ -156,6 +312,113 @@ func (m *DefaultMetrics) UpdateDBConnections(active, idle, total int) {
 	m.dbConnectionsTotal.Set(float64(total))
 }
 
+// RecordCacheAccess 记录缓存访问的命中情况
+func (m *DefaultMetrics) RecordCacheAccess(tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheRequestsTotal.WithLabelValues(tier, result).Inc()
+}
+
+// RecordCacheCommand 记录缓存命令持续时间
+func (m *DefaultMetrics) RecordCacheCommand(command string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	m.cacheCommandDuration.WithLabelValues(command, status).Observe(duration.Seconds())
+}
+
+// UpdateCachePoolStats 更新指定连接池的指标，hits、misses和timeouts为go-redis连接池的累计值
+func (m *DefaultMetrics) UpdateCachePoolStats(pool string, hits, misses, timeouts, idle, total int) {
+	m.cachePoolCounters.update(pool, hits, misses, timeouts)
+	m.cachePoolIdleConns.WithLabelValues(pool).Set(float64(idle))
+	m.cachePoolTotalConns.WithLabelValues(pool).Set(float64(total))
+}
+
+// cachePoolCollector 将go-redis连接池的累计统计按计数器导出
+type cachePoolCollector struct {
+	hits     *prometheus.Desc
+	misses   *prometheus.Desc
+	timeouts *prometheus.Desc
+
+	mu    sync.Mutex
+	pools map[string][3]int
+}
+
+// newCachePoolCollector 创建连接池计数器收集器
+func newCachePoolCollector(namespace string) *cachePoolCollector {
+	return &cachePoolCollector{
+		hits: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cache_pool_hits_total"),
+			"Number of times a free connection was found in the cache pool", []string{"pool"}, nil),
+		misses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cache_pool_misses_total"),
+			"Number of times a free connection was not found in the cache pool", []string{"pool"}, nil),
+		timeouts: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "cache_pool_timeouts_total"),
+			"Number of times a wait timeout occurred in the cache pool", []string{"pool"}, nil),
+		pools: make(map[string][3]int),
+	}
+}
+
+// update 保存连接池最新的累计值
+func (c *cachePoolCollector) update(pool string, hits, misses, timeouts int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools[pool] = [3]int{hits, misses, timeouts}
+}
+
+// Describe 实现prometheus.Collector
+func (c *cachePoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+}
+
+// Collect 实现prometheus.Collector
+func (c *cachePoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for pool, stats := range c.pools {
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats[0]), pool)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats[1]), pool)
+		ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats[2]), pool)
+	}
+}
+
+// cacheCircuitStates 缓存熔断器的全部状态
+var cacheCircuitStates = []string{"closed", "half_open", "open"}
+
+// UpdateCacheCircuitState 更新指定熔断器的状态，当前状态为1，其余状态为0
+func (m *DefaultMetrics) UpdateCacheCircuitState(name, state string) {
+	for _, s := range cacheCircuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		m.cacheCircuitState.WithLabelValues(name, s).Set(value)
+	}
+}
+
+// UpdateAdaptiveLimit 更新自适应限流器当前的并发上限
+func (m *DefaultMetrics) UpdateAdaptiveLimit(limiter string, limit int) {
+	m.adaptiveLimit.WithLabelValues(limiter).Set(float64(limit))
+}
+
+// RecordRateLimitError 记录限流器后端错误，policy为出错时采取的失败策略
+func (m *DefaultMetrics) RecordRateLimitError(policy string) {
+	m.rateLimitErrors.WithLabelValues(policy).Inc()
+}
+
+// RecordRateLimitDecision 记录限流决策，outcome为allowed、denied或shadow_denied
+func (m *DefaultMetrics) RecordRateLimitDecision(rule, outcome string) {
+	m.rateLimitDecisions.WithLabelValues(rule, outcome).Inc()
+}
+
+// RecordConcurrencyLeaseLost 记录请求仍在执行时并发租约被清理，reacquired表示是否重新获取到名额
+func (m *DefaultMetrics) RecordConcurrencyLeaseLost(reacquired bool) {
+	m.concurrencyLeasesLost.WithLabelValues(strconv.FormatBool(reacquired)).Inc()
+}
+
 // RecordBusinessMetric 记录业务指标
 func (m *DefaultMetrics) RecordBusinessMetric(metricType string) {
 	m.businessMetrics.WithLabelValues(metricType).Inc()
@@ -0,0 +1,404 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 自适应限流算法
+const (
+	AdaptiveAIMD     = "aimd"     // 加性增、乘性减
+	AdaptiveGradient = "gradient" // 根据长短期延迟比值调整
+)
+
+// longRTTFactor 梯度算法中长期延迟的指数平均系数，约等于最近100个请求
+const longRTTFactor = 0.01
+
+// Priority 请求优先级，负载升高时先拒绝低优先级请求
+type Priority int
+
+// 请求优先级
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// priorityShares 各优先级可使用的并发上限比例
+var priorityShares = [...]float64{
+	PriorityLow:      0.5,
+	PriorityNormal:   0.8,
+	PriorityHigh:     0.95,
+	PriorityCritical: 1,
+}
+
+// ParsePriority 解析优先级名称(low, normal, high, critical)或数字(0~3)，无法识别时为PriorityNormal
+func ParsePriority(value string) Priority {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "low":
+		return PriorityLow
+	case "high":
+		return PriorityHigh
+	case "critical":
+		return PriorityCritical
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= int(PriorityLow) && n <= int(PriorityCritical) {
+		return Priority(n)
+	}
+	return PriorityNormal
+}
+
+// AdaptiveConfig 自适应限流配置
+type AdaptiveConfig struct {
+	Algorithm      string        `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"`                   // aimd(默认) 或 gradient
+	InitialLimit   int           `yaml:"initial_limit" json:"initial_limit" mapstructure:"initial_limit"`       // 初始并发上限
+	MinLimit       int           `yaml:"min_limit" json:"min_limit" mapstructure:"min_limit"`                   // 并发上限的最小值
+	MaxLimit       int           `yaml:"max_limit" json:"max_limit" mapstructure:"max_limit"`                   // 并发上限的最大值
+	BackoffRatio   float64       `yaml:"backoff_ratio" json:"backoff_ratio" mapstructure:"backoff_ratio"`       // AIMD过载时并发上限的缩减比例
+	Timeout        time.Duration `yaml:"timeout" json:"timeout" mapstructure:"timeout"`                         // AIMD延迟超过该值视为过载，0表示只根据失败判断
+	Smoothing      float64       `yaml:"smoothing" json:"smoothing" mapstructure:"smoothing"`                   // 梯度算法的平滑系数(0~1)
+	SampleWindow   time.Duration `yaml:"sample_window" json:"sample_window" mapstructure:"sample_window"`       // 梯度算法汇总延迟样本的时间窗口
+	CPUThreshold   float64       `yaml:"cpu_threshold" json:"cpu_threshold" mapstructure:"cpu_threshold"`       // CPU使用率(0~1)超过该值视为过载，0表示不采样CPU
+	CPUInterval    time.Duration `yaml:"cpu_interval" json:"cpu_interval" mapstructure:"cpu_interval"`          // CPU采样间隔
+	PriorityHeader string        `yaml:"priority_header" json:"priority_header" mapstructure:"priority_header"` // 携带请求优先级的元数据名称
+}
+
+// DefaultAdaptiveConfig 默认自适应限流配置
+func DefaultAdaptiveConfig() *AdaptiveConfig {
+	return &AdaptiveConfig{
+		Algorithm:      AdaptiveAIMD,
+		InitialLimit:   20,
+		MinLimit:       1,
+		MaxLimit:       1000,
+		BackoffRatio:   0.9,
+		Smoothing:      0.2,
+		SampleWindow:   time.Second,
+		CPUInterval:    time.Second,
+		PriorityHeader: "x-priority",
+	}
+}
+
+// AdaptiveLimiter 自适应并发限制器
+// 根据请求延迟、失败和CPU使用率调整并发上限，低优先级请求只能使用部分并发上限
+type AdaptiveLimiter struct {
+	name    string
+	config  AdaptiveConfig
+	metrics metrics.RateLimitMetrics
+	cpu     *cpuSampler
+	now     func() time.Time
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	reported int
+
+	// 梯度算法状态
+	longRTT     float64   // 长期平均延迟(纳秒)
+	windowStart time.Time // 当前采样窗口的开始时间
+	samples     int       // 当前窗口的样本数
+	rttSum      float64   // 当前窗口的延迟总和(纳秒)
+	maxInFlight int       // 当前窗口的最大并发数
+	dropped     bool      // 当前窗口是否出现过载失败
+}
+
+// NewAdaptiveLimiter 创建自适应并发限制器，name用于指标标签，collector为nil或未实现 metrics.RateLimitMetrics 时不记录指标
+// 启用CPU采样时需要调用Close停止采样
+func NewAdaptiveLimiter(name string, config *AdaptiveConfig, collector metrics.MetricsCollector) (*AdaptiveLimiter, error) {
+	defaults := DefaultAdaptiveConfig()
+	if config == nil {
+		config = defaults
+	}
+	c := *config
+	if c.Algorithm == "" {
+		c.Algorithm = defaults.Algorithm
+	}
+	if c.Algorithm != AdaptiveAIMD && c.Algorithm != AdaptiveGradient {
+		return nil, fmt.Errorf("unsupported adaptive algorithm: %s", c.Algorithm)
+	}
+	if c.MinLimit <= 0 {
+		c.MinLimit = defaults.MinLimit
+	}
+	if c.MaxLimit <= 0 {
+		c.MaxLimit = defaults.MaxLimit
+	}
+	if c.MaxLimit < c.MinLimit {
+		c.MaxLimit = c.MinLimit
+	}
+	if c.InitialLimit <= 0 {
+		c.InitialLimit = defaults.InitialLimit
+	}
+	if c.BackoffRatio <= 0 || c.BackoffRatio >= 1 {
+		c.BackoffRatio = defaults.BackoffRatio
+	}
+	if c.Smoothing <= 0 || c.Smoothing > 1 {
+		c.Smoothing = defaults.Smoothing
+	}
+	if c.SampleWindow <= 0 {
+		c.SampleWindow = defaults.SampleWindow
+	}
+	if c.CPUInterval <= 0 {
+		c.CPUInterval = defaults.CPUInterval
+	}
+	if c.PriorityHeader == "" {
+		c.PriorityHeader = defaults.PriorityHeader
+	}
+
+	l := &AdaptiveLimiter{
+		name:    name,
+		config:  c,
+		metrics: rateLimitMetrics(collector),
+		now:     time.Now,
+		limit:   math.Max(float64(c.MinLimit), math.Min(float64(c.MaxLimit), float64(c.InitialLimit))),
+	}
+	if c.CPUThreshold > 0 {
+		sampler, err := newCPUSampler(c.CPUInterval)
+		if err != nil {
+			return nil, err
+		}
+		l.cpu = sampler
+	}
+	l.reportLimit()
+	return l, nil
+}
+
+// Acquire 按优先级获取一个并发名额，名额不足时返回false。
+// 成功时返回done，请求结束后调用done报告请求是否因过载失败（超时、资源耗尽等）
+func (l *AdaptiveLimiter) Acquire(priority Priority) (done func(dropped bool), ok bool) {
+	if priority < PriorityLow || priority > PriorityCritical {
+		priority = PriorityNormal
+	}
+
+	l.mu.Lock()
+	// CPU过载时不再接受低优先级请求
+	if priority == PriorityLow && l.cpuOverloaded() {
+		l.mu.Unlock()
+		return nil, false
+	}
+	allowed := math.Max(1, math.Floor(l.limit*priorityShares[priority]))
+	if float64(l.inFlight) >= allowed {
+		l.mu.Unlock()
+		return nil, false
+	}
+	l.inFlight++
+	inFlight := l.inFlight
+	l.mu.Unlock()
+
+	start := l.now()
+	var once sync.Once
+	return func(dropped bool) {
+		once.Do(func() {
+			end := l.now()
+			l.onSample(end, end.Sub(start), inFlight, dropped)
+		})
+	}, true
+}
+
+// Limit 当前并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight 当前正在处理的请求数
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Close 停止CPU采样
+func (l *AdaptiveLimiter) Close() {
+	if l.cpu != nil {
+		l.cpu.Close()
+	}
+}
+
+// PriorityFromContext 从请求元数据中读取优先级，未设置时为PriorityNormal
+func (l *AdaptiveLimiter) PriorityFromContext(ctx context.Context) Priority {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(l.config.PriorityHeader); len(values) > 0 {
+			return ParsePriority(values[0])
+		}
+	}
+	return PriorityNormal
+}
+
+// onSample 根据一次请求的结果调整并发上限
+func (l *AdaptiveLimiter) onSample(now time.Time, rtt time.Duration, inFlight int, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	if l.cpuOverloaded() {
+		dropped = true
+	}
+
+	switch l.config.Algorithm {
+	case AdaptiveAIMD:
+		if dropped || (l.config.Timeout > 0 && rtt > l.config.Timeout) {
+			l.limit *= l.config.BackoffRatio
+		} else if float64(inFlight)*2 >= l.limit {
+			// 只有并发上限被充分使用时才增加
+			l.limit++
+		}
+	case AdaptiveGradient:
+		if !l.gradientSample(now, rtt, inFlight, dropped) {
+			return
+		}
+	}
+
+	l.limit = math.Max(float64(l.config.MinLimit), math.Min(float64(l.config.MaxLimit), l.limit))
+	l.reportLimit()
+}
+
+// gradientSample 汇总一个采样窗口内的延迟，窗口结束时调整并发上限，返回是否已调整
+func (l *AdaptiveLimiter) gradientSample(now time.Time, rtt time.Duration, inFlight int, dropped bool) bool {
+	if l.windowStart.IsZero() {
+		l.windowStart = now
+	}
+	l.samples++
+	l.rttSum += math.Max(1, float64(rtt))
+	l.maxInFlight = max(l.maxInFlight, inFlight)
+	l.dropped = l.dropped || dropped
+	if now.Sub(l.windowStart) < l.config.SampleWindow {
+		return false
+	}
+
+	sample := l.rttSum / float64(l.samples)
+	if l.longRTT == 0 {
+		l.longRTT = sample
+	} else {
+		l.longRTT = l.longRTT*(1-longRTTFactor) + sample*longRTTFactor
+	}
+
+	// 短期延迟高于长期延迟时按比例缩减，并保留sqrt(limit)的排队余量
+	gradient := math.Max(0.5, math.Min(1, l.longRTT/sample))
+	if l.dropped {
+		gradient = 0.5
+	}
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	// 并发上限未被充分使用时不增加
+	if float64(l.maxInFlight)*2 < l.limit {
+		newLimit = math.Min(newLimit, l.limit)
+	}
+	l.limit = l.limit*(1-l.config.Smoothing) + newLimit*l.config.Smoothing
+
+	l.windowStart, l.samples, l.rttSum, l.maxInFlight, l.dropped = now, 0, 0, 0, false
+	return true
+}
+
+// cpuOverloaded 判断CPU使用率是否超过阈值
+func (l *AdaptiveLimiter) cpuOverloaded() bool {
+	return l.cpu != nil && l.cpu.Usage() > l.config.CPUThreshold
+}
+
+// reportLimit 并发上限的整数值变化时更新指标，调用方需持有锁
+func (l *AdaptiveLimiter) reportLimit() {
+	if l.metrics == nil || int(l.limit) == l.reported {
+		return
+	}
+	l.reported = int(l.limit)
+	l.metrics.UpdateAdaptiveLimit(l.name, l.reported)
+}
+
+// AdaptiveInterceptorConfig 自适应限流拦截器配置
+type AdaptiveInterceptorConfig struct {
+	Limiter      *AdaptiveLimiter
+	PriorityFunc func(ctx context.Context) Priority // 默认从Limiter配置的元数据读取
+	SkipFunc     SkipFunc
+}
+
+// UnaryServerAdaptiveInterceptor 创建自适应限流的一元服务器拦截器
+func UnaryServerAdaptiveInterceptor(config *AdaptiveInterceptorConfig) grpc.UnaryServerInterceptor {
+	if config.PriorityFunc == nil {
+		config.PriorityFunc = config.Limiter.PriorityFromContext
+	}
+	if config.SkipFunc == nil {
+		config.SkipFunc = DefaultSkipFunc
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if config.SkipFunc(ctx, info) {
+			return handler(ctx, req)
+		}
+
+		done, ok := config.Limiter.Acquire(config.PriorityFunc(ctx))
+		if !ok {
+			return nil, status.Error(codes.ResourceExhausted, "server is overloaded")
+		}
+
+		// handler panic时同样释放并发槽位，panic按过载处理后继续向外层恢复拦截器传递
+		panicked := true
+		defer func() {
+			done(panicked || isOverloadError(ctx, err))
+		}()
+		resp, err = handler(ctx, req)
+		panicked = false
+		return resp, err
+	}
+}
+
+// StreamServerAdaptiveInterceptor 创建自适应限流的流服务器拦截器
+func StreamServerAdaptiveInterceptor(config *AdaptiveInterceptorConfig) grpc.StreamServerInterceptor {
+	if config.PriorityFunc == nil {
+		config.PriorityFunc = config.Limiter.PriorityFromContext
+	}
+	if config.SkipFunc == nil {
+		config.SkipFunc = DefaultSkipFunc
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := ss.Context()
+		unaryInfo := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: info.FullMethod,
+		}
+
+		if config.SkipFunc(ctx, unaryInfo) {
+			return handler(srv, ss)
+		}
+
+		done, ok := config.Limiter.Acquire(config.PriorityFunc(ctx))
+		if !ok {
+			return status.Error(codes.ResourceExhausted, "server is overloaded")
+		}
+
+		panicked := true
+		defer func() {
+			done(panicked || isOverloadError(ctx, err))
+		}()
+		err = handler(srv, ss)
+		panicked = false
+		return err
+	}
+}
+
+// isOverloadError 判断请求失败是否由过载引起
+func isOverloadError(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// limitRecorder 记录自适应限流器上报的并发上限
+type limitRecorder struct {
+	metrics.MetricsCollector
+	metrics.RateLimitMetrics
+	limits []int
+}
+
+func (r *limitRecorder) UpdateAdaptiveLimit(limiter string, limit int) {
+	r.limits = append(r.limits, limit)
+}
+
+// TestAdaptiveAIMD 测试AIMD在成功时增加、过载时缩减并发上限
+func TestAdaptiveAIMD(t *testing.T) {
+	recorder := &limitRecorder{}
+	limiter, err := NewAdaptiveLimiter("test", &AdaptiveConfig{InitialLimit: 4, MaxLimit: 6, Timeout: 100 * time.Millisecond}, recorder)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter.now = clock.Now
+
+	// 充分使用并发上限时逐步增加，不超过最大值
+	for i := 0; i < 5; i++ {
+		var dones []func(bool)
+		for j := 0; j < (limiter.Limit()+1)/2; j++ {
+			done, ok := limiter.Acquire(PriorityCritical)
+			if !ok {
+				t.Fatalf("Expected acquire to succeed")
+			}
+			dones = append(dones, done)
+		}
+		for _, done := range dones {
+			done(false)
+		}
+	}
+	if limiter.Limit() != 6 {
+		t.Errorf("Expected limit to grow to max 6, got %d", limiter.Limit())
+	}
+
+	done, _ := limiter.Acquire(PriorityNormal)
+	done(true)
+	if limiter.Limit() != 5 {
+		t.Errorf("Expected limit to back off to 5, got %d", limiter.Limit())
+	}
+
+	done, _ = limiter.Acquire(PriorityNormal)
+	clock.Advance(200 * time.Millisecond)
+	done(false)
+	if limiter.Limit() != 4 {
+		t.Errorf("Expected slow request to reduce limit to 4, got %d", limiter.Limit())
+	}
+
+	if len(recorder.limits) == 0 || recorder.limits[0] != 4 || recorder.limits[len(recorder.limits)-1] != 4 {
+		t.Errorf("Unexpected reported limits: %v", recorder.limits)
+	}
+	if limiter.InFlight() != 0 {
+		t.Errorf("Expected no in-flight requests, got %d", limiter.InFlight())
+	}
+}
+
+// TestAdaptiveGradient 测试梯度算法在延迟升高时缩减并发上限
+func TestAdaptiveGradient(t *testing.T) {
+	limiter, err := NewAdaptiveLimiter("test", &AdaptiveConfig{Algorithm: AdaptiveGradient, InitialLimit: 20, MaxLimit: 1000, SampleWindow: 10 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter.now = clock.Now
+
+	request := func(latency time.Duration, concurrency int) {
+		var dones []func(bool)
+		for i := 0; i < concurrency; i++ {
+			if done, ok := limiter.Acquire(PriorityCritical); ok {
+				dones = append(dones, done)
+			}
+		}
+		clock.Advance(latency)
+		for _, done := range dones {
+			done(false)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		request(10*time.Millisecond, limiter.Limit())
+	}
+	grown := limiter.Limit()
+	if grown <= 20 {
+		t.Errorf("Expected limit to grow under stable latency, got %d", grown)
+	}
+
+	for i := 0; i < 5; i++ {
+		request(50*time.Millisecond, limiter.Limit())
+	}
+	if limiter.Limit() >= grown {
+		t.Errorf("Expected limit to shrink when latency increases, got %d (was %d)", limiter.Limit(), grown)
+	}
+}
+
+// TestAdaptivePriority 测试低优先级请求只能使用部分并发上限
+func TestAdaptivePriority(t *testing.T) {
+	limiter, _ := NewAdaptiveLimiter("test", &AdaptiveConfig{InitialLimit: 10}, nil)
+
+	var dones []func(bool)
+	for {
+		done, ok := limiter.Acquire(PriorityLow)
+		if !ok {
+			break
+		}
+		dones = append(dones, done)
+	}
+	if len(dones) != 5 {
+		t.Errorf("Expected low priority to use 5 of 10 slots, got %d", len(dones))
+	}
+	for i := 0; i < 3; i++ {
+		done, ok := limiter.Acquire(PriorityNormal)
+		if !ok {
+			t.Fatalf("Expected normal priority request %d to be admitted", i)
+		}
+		dones = append(dones, done)
+	}
+	if _, ok := limiter.Acquire(PriorityNormal); ok {
+		t.Error("Expected normal priority to be limited to 8 slots")
+	}
+	if _, ok := limiter.Acquire(PriorityCritical); !ok {
+		t.Error("Expected critical priority to use remaining slots")
+	}
+
+	cases := map[string]Priority{"low": PriorityLow, "HIGH": PriorityHigh, "3": PriorityCritical, "": PriorityNormal, "9": PriorityNormal}
+	for value, expected := range cases {
+		if got := ParsePriority(value); got != expected {
+			t.Errorf("ParsePriority(%q): expected %d, got %d", value, expected, got)
+		}
+	}
+}
+
+// TestAdaptiveInterceptor 测试拦截器按元数据中的优先级限流并根据错误调整上限
+func TestAdaptiveInterceptor(t *testing.T) {
+	limiter, _ := NewAdaptiveLimiter("test", &AdaptiveConfig{InitialLimit: 2, MinLimit: 1, BackoffRatio: 0.5}, nil)
+	interceptor := UnaryServerAdaptiveInterceptor(&AdaptiveInterceptorConfig{Limiter: limiter})
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+
+	low := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-priority", "low"))
+	entered := make(chan struct{})
+	finish := make(chan struct{})
+	go interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(entered)
+		<-finish
+		return nil, nil
+	})
+	<-entered
+
+	_, err := interceptor(low, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected low priority request to be shed, got %v", err)
+	}
+	close(finish)
+	for limiter.InFlight() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "database unavailable")
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected handler error to be returned, got %v", err)
+	}
+	if limiter.Limit() != 1 {
+		t.Errorf("Expected overload error to reduce limit to 1, got %d", limiter.Limit())
+	}
+}
+
+// TestAdaptiveInterceptorPanic 测试handler panic时释放并发槽位并按过载缩减上限
+func TestAdaptiveInterceptorPanic(t *testing.T) {
+	limiter, _ := NewAdaptiveLimiter("test", &AdaptiveConfig{InitialLimit: 2, MinLimit: 1, BackoffRatio: 0.5}, nil)
+	interceptor := UnaryServerAdaptiveInterceptor(&AdaptiveInterceptorConfig{Limiter: limiter})
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic to propagate to outer interceptor")
+			}
+		}()
+		interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	if limiter.InFlight() != 0 {
+		t.Errorf("Expected slot to be released after panic, got %d in flight", limiter.InFlight())
+	}
+	if limiter.Limit() != 1 {
+		t.Errorf("Expected panic to be treated as overload, got limit %d", limiter.Limit())
+	}
+}
+
+// TestCPUSampler 测试CPU采样，平台不支持时跳过
+func TestCPUSampler(t *testing.T) {
+	sampler, err := newCPUSampler(10 * time.Millisecond)
+	if err != nil {
+		t.Skipf("cpu sampling not supported: %v", err)
+	}
+	defer sampler.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	if usage := sampler.Usage(); usage < 0 || usage > 1 {
+		t.Errorf("Expected usage between 0 and 1, got %f", usage)
+	}
+}
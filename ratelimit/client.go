@@ -0,0 +1,244 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ClientKeyFunc 生成客户端限流key的函数，target为连接的目标地址
+type ClientKeyFunc func(ctx context.Context, target, method string) string
+
+// TargetMethodKeyFunc 按目标地址和方法限流
+func TargetMethodKeyFunc(ctx context.Context, target, method string) string {
+	return target + method
+}
+
+// ClientLimitConfig 客户端限流配置
+type ClientLimitConfig struct {
+	Limit      int           `yaml:"limit" json:"limit" mapstructure:"limit"`                   // 每个目标和方法在窗口内允许的调用数，0表示不在本地限流
+	Window     time.Duration `yaml:"window" json:"window" mapstructure:"window"`                // 时间窗口
+	Burst      int           `yaml:"burst" json:"burst" mapstructure:"burst"`                   // 突发容量，默认等于Limit
+	MaxWait    time.Duration `yaml:"max_wait" json:"max_wait" mapstructure:"max_wait"`          // 被限流时最长等待时间，超过或超出调用deadline时立即失败，0表示不等待
+	MaxRetries int           `yaml:"max_retries" json:"max_retries" mapstructure:"max_retries"` // 服务端返回RetryInfo时一元调用的最多重试次数
+}
+
+// DefaultClientLimitConfig 默认客户端限流配置
+func DefaultClientLimitConfig() *ClientLimitConfig {
+	return &ClientLimitConfig{
+		Window:     time.Second,
+		MaxWait:    time.Second,
+		MaxRetries: 1,
+	}
+}
+
+// ClientLimiter 客户端限流器
+// 按key使用本地令牌桶限制发出的调用，并在服务端返回RetryInfo后暂停该key的调用直到建议的重试时间。
+// 需要等待时，等待时间不超过MaxWait且不超出调用deadline则等待，否则立即返回ResourceExhausted
+type ClientLimiter struct {
+	config  ClientLimitConfig
+	limiter RateLimiter
+	keyFunc ClientKeyFunc
+
+	mu      sync.Mutex
+	blocked map[string]time.Time // 服务端要求暂停调用的key及其恢复时间
+}
+
+// NewClientLimiter 创建客户端限流器，keyFunc为nil时按目标地址和方法限流
+func NewClientLimiter(config *ClientLimitConfig, keyFunc ClientKeyFunc) *ClientLimiter {
+	defaults := DefaultClientLimitConfig()
+	if config == nil {
+		config = defaults
+	}
+	c := *config
+	if c.Window <= 0 {
+		c.Window = defaults.Window
+	}
+	if c.MaxWait < 0 {
+		c.MaxWait = 0
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	}
+	if keyFunc == nil {
+		keyFunc = TargetMethodKeyFunc
+	}
+
+	l := &ClientLimiter{
+		config:  c,
+		keyFunc: keyFunc,
+		blocked: make(map[string]time.Time),
+	}
+	if c.Limit > 0 {
+		l.limiter = NewMemoryTokenBucketLimiter(c.Limit, c.Window, c.Burst)
+	}
+	return l
+}
+
+// acquire 等待key可以发起调用，无法在MaxWait和deadline内等到时返回错误
+func (l *ClientLimiter) acquire(ctx context.Context, key string) error {
+	for {
+		if d := l.blockedFor(key); d > 0 {
+			if err := l.wait(ctx, d); err != nil {
+				return err
+			}
+			continue
+		}
+		if l.limiter == nil {
+			return nil
+		}
+
+		decision, err := l.limiter.Decide(ctx, key, 1)
+		if err != nil || decision.Allowed {
+			return nil
+		}
+		if decision.RetryAfter < 0 {
+			return clientLimitError(0)
+		}
+		if err := l.wait(ctx, decision.RetryAfter); err != nil {
+			return err
+		}
+	}
+}
+
+// wait 等待d，超过MaxWait或调用deadline时立即返回ResourceExhausted
+func (l *ClientLimiter) wait(ctx context.Context, d time.Duration) error {
+	if d > l.config.MaxWait {
+		return clientLimitError(d)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return clientLimitError(d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// block 在d时间内暂停key的调用
+func (l *ClientLimiter) block(key string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until := time.Now().Add(d)
+	if until.After(l.blocked[key]) {
+		l.blocked[key] = until
+	}
+}
+
+// blockedFor 返回key还需暂停的时间，已恢复的key被删除
+func (l *ClientLimiter) blockedFor(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.blocked[key]
+	if !ok {
+		return 0
+	}
+	d := time.Until(until)
+	if d <= 0 {
+		delete(l.blocked, key)
+	}
+	return d
+}
+
+// observe 服务端返回RetryInfo时暂停key的调用，返回是否可以重试
+func (l *ClientLimiter) observe(key string, err error) bool {
+	retryAfter, ok := serverRetryAfter(err)
+	if ok {
+		l.block(key, retryAfter)
+	}
+	return ok
+}
+
+// serverRetryAfter 从ResourceExhausted或Unavailable错误中读取RetryInfo建议的等待时间
+func serverRetryAfter(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || (st.Code() != codes.ResourceExhausted && st.Code() != codes.Unavailable) {
+		return 0, false
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// clientLimitError 客户端限流错误，d>0时携带RetryInfo
+func clientLimitError(d time.Duration) error {
+	st := status.New(codes.ResourceExhausted, "client rate limit exceeded")
+	if d > 0 {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)}); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+// UnaryClientInterceptor 创建一元客户端限流拦截器
+// 服务端返回RetryInfo时，在MaxRetries次数内按建议时间等待后重试，无法等待时返回服务端的错误
+func UnaryClientInterceptor(limiter *ClientLimiter) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key := limiter.keyFunc(ctx, cc.Target(), method)
+
+		var lastErr error
+		for attempt := 0; ; attempt++ {
+			if err := limiter.acquire(ctx, key); err != nil {
+				if lastErr != nil {
+					return lastErr
+				}
+				return err
+			}
+
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if !limiter.observe(key, err) || attempt >= limiter.config.MaxRetries {
+				return err
+			}
+			lastErr = err
+		}
+	}
+}
+
+// StreamClientInterceptor 创建流客户端限流拦截器
+// 只在建立流之前限流，流中收到的RetryInfo会暂停后续调用但不会重试当前流
+func StreamClientInterceptor(limiter *ClientLimiter) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		key := limiter.keyFunc(ctx, cc.Target(), method)
+		if err := limiter.acquire(ctx, key); err != nil {
+			return nil, err
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			limiter.observe(key, err)
+			return nil, err
+		}
+		return &retryAwareClientStream{ClientStream: stream, limiter: limiter, key: key}, nil
+	}
+}
+
+// retryAwareClientStream 记录流中服务端返回的RetryInfo
+type retryAwareClientStream struct {
+	grpc.ClientStream
+	limiter *ClientLimiter
+	key     string
+}
+
+// RecvMsg 接收消息
+func (s *retryAwareClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.limiter.observe(s.key, err)
+	}
+	return err
+}
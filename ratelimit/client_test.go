@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestClientConn 创建不会实际建连的客户端连接
+func newTestClientConn(t *testing.T) *grpc.ClientConn {
+	conn, err := grpc.NewClient("passthrough:///test", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client conn: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// retryAfterError 携带RetryInfo的限流错误
+func retryAfterError(d time.Duration) error {
+	st, _ := status.New(codes.ResourceExhausted, "rate limit exceeded").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)})
+	return st.Err()
+}
+
+// TestUnaryClientInterceptorThrottle 测试本地令牌桶限流的等待和快速失败
+func TestUnaryClientInterceptorThrottle(t *testing.T) {
+	conn := newTestClientConn(t)
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return nil
+	}
+
+	failFast := UnaryClientInterceptor(NewClientLimiter(&ClientLimitConfig{Limit: 1, Window: time.Minute}, nil))
+	if err := failFast(context.Background(), "/test.Service/Method", nil, nil, conn, invoker); err != nil {
+		t.Fatalf("Expected first call to pass, got %v", err)
+	}
+	err := failFast(context.Background(), "/test.Service/Method", nil, nil, conn, invoker)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if _, ok := serverRetryAfter(err); !ok {
+		t.Error("Expected RetryInfo in client limit error")
+	}
+	// 其他方法使用独立的配额
+	if err := failFast(context.Background(), "/test.Service/Other", nil, nil, conn, invoker); err != nil {
+		t.Errorf("Expected other method to pass, got %v", err)
+	}
+
+	waiting := UnaryClientInterceptor(NewClientLimiter(&ClientLimitConfig{
+		Limit: 1, Window: 50 * time.Millisecond, MaxWait: time.Second,
+	}, nil))
+	waiting(context.Background(), "/test.Service/Method", nil, nil, conn, invoker)
+	start := time.Now()
+	if err := waiting(context.Background(), "/test.Service/Method", nil, nil, conn, invoker); err != nil {
+		t.Fatalf("Expected call to wait for a token, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected call to wait, took %v", elapsed)
+	}
+}
+
+// TestUnaryClientInterceptorRetryInfo 测试按服务端RetryInfo等待重试，或在deadline不足时快速失败
+func TestUnaryClientInterceptorRetryInfo(t *testing.T) {
+	conn := newTestClientConn(t)
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if calls == 1 {
+			return retryAfterError(50 * time.Millisecond)
+		}
+		return nil
+	}
+
+	interceptor := UnaryClientInterceptor(NewClientLimiter(nil, nil))
+	if err := interceptor(context.Background(), "/test.Service/Method", nil, nil, conn, invoker); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+
+	// deadline不足以等待时直接返回服务端错误，并在暂停期间不再发起调用
+	calls = 0
+	interceptor = UnaryClientInterceptor(NewClientLimiter(nil, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := interceptor(ctx, "/test.Service/Method", nil, nil, conn, invoker)
+	if status.Code(err) != codes.ResourceExhausted || status.Convert(err).Message() != "rate limit exceeded" {
+		t.Fatalf("Expected server error, got %v", err)
+	}
+	err = interceptor(ctx, "/test.Service/Method", nil, nil, conn, invoker)
+	if status.Code(err) != codes.ResourceExhausted || calls != 1 {
+		t.Errorf("Expected fail fast without calling server, got %v after %d calls", err, calls)
+	}
+}
+
+// TestStreamClientInterceptorRetryInfo 测试流中收到RetryInfo后暂停后续调用
+func TestStreamClientInterceptorRetryInfo(t *testing.T) {
+	conn := newTestClientConn(t)
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeClientStream{err: retryAfterError(time.Minute)}, nil
+	}
+
+	interceptor := StreamClientInterceptor(NewClientLimiter(nil, nil))
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{}, conn, "/test.Service/Stream", streamer)
+	if err != nil {
+		t.Fatalf("Expected stream to open, got %v", err)
+	}
+	stream.RecvMsg(nil)
+
+	if _, err := interceptor(context.Background(), &grpc.StreamDesc{}, conn, "/test.Service/Stream", streamer); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted after RetryInfo, got %v", err)
+	}
+}
+
+// fakeClientStream RecvMsg返回固定错误的客户端流
+type fakeClientStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (s *fakeClientStream) RecvMsg(m interface{}) error { return s.err }
@@ -0,0 +1,375 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/cache"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrConcurrencyLimited 并发名额已满且排队超时
+var ErrConcurrencyLimited = errors.New("ratelimit: too many concurrent requests")
+
+// ConcurrencyLimiter 并发数限制器
+type ConcurrencyLimiter interface {
+	// Acquire 获取一个并发名额，成功时返回释放函数（可重复调用）。
+	// 名额已满时最多排队等待maxWait或直到ctx结束，超时返回ErrConcurrencyLimited
+	Acquire(ctx context.Context, key string) (release func(), err error)
+}
+
+// LocalConcurrencyLimiter 进程内的并发数限制器
+type LocalConcurrencyLimiter struct {
+	limit   int
+	maxWait time.Duration
+
+	mu    sync.Mutex
+	slots map[string]*localSlot
+}
+
+// localSlot 单个key的信号量，引用计数归零时删除
+type localSlot struct {
+	sem  *semaphore.Weighted
+	refs int
+}
+
+// NewLocalConcurrencyLimiter 创建进程内并发数限制器，maxWait为名额已满时的最长排队时间，0表示立即拒绝
+func NewLocalConcurrencyLimiter(limit int, maxWait time.Duration) *LocalConcurrencyLimiter {
+	return &LocalConcurrencyLimiter{
+		limit:   limit,
+		maxWait: maxWait,
+		slots:   make(map[string]*localSlot),
+	}
+}
+
+// Acquire 获取一个并发名额
+func (l *LocalConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	slot := l.ref(key)
+
+	if !slot.sem.TryAcquire(1) {
+		if err := l.wait(ctx, slot); err != nil {
+			l.unref(key, slot)
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			slot.sem.Release(1)
+			l.unref(key, slot)
+		})
+	}, nil
+}
+
+// wait 排队等待名额
+func (l *LocalConcurrencyLimiter) wait(ctx context.Context, slot *localSlot) error {
+	if l.maxWait <= 0 {
+		return ErrConcurrencyLimited
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, l.maxWait)
+	defer cancel()
+	if err := slot.sem.Acquire(waitCtx, 1); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrConcurrencyLimited
+	}
+	return nil
+}
+
+// ref 获取key的信号量并增加引用计数
+func (l *LocalConcurrencyLimiter) ref(key string) *localSlot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slot, ok := l.slots[key]
+	if !ok {
+		slot = &localSlot{sem: semaphore.NewWeighted(int64(l.limit))}
+		l.slots[key] = slot
+	}
+	slot.refs++
+	return slot
+}
+
+// unref 减少引用计数，没有持有者和等待者时删除信号量
+func (l *LocalConcurrencyLimiter) unref(key string, slot *localSlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if slot.refs--; slot.refs == 0 {
+		delete(l.slots, key)
+	}
+}
+
+// concurrencyAcquireScript 清理过期租约并在未满时加入新租约，ZSET成员为租约ID，分数为过期时间(毫秒)
+var concurrencyAcquireScript = cache.RegisterScript("ratelimit:concurrency:acquire", `
+	local key = KEYS[1]
+	local now = tonumber(ARGV[1])
+	local lease = tonumber(ARGV[2])
+	local limit = tonumber(ARGV[3])
+	local id = ARGV[4]
+
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+	if redis.call('ZCARD', key) >= limit then
+		return 0
+	end
+	redis.call('ZADD', key, now + lease, id)
+	redis.call('PEXPIRE', key, lease)
+	return 1
+`)
+
+// concurrencyRenewScript 续约，租约已被清理时返回0
+var concurrencyRenewScript = cache.RegisterScript("ratelimit:concurrency:renew", `
+	local key = KEYS[1]
+	local expire_at = tonumber(ARGV[1])
+	local lease = tonumber(ARGV[2])
+	local id = ARGV[3]
+
+	if redis.call('ZADD', key, 'XX', 'CH', expire_at, id) == 0 and not redis.call('ZSCORE', key, id) then
+		return 0
+	end
+	if redis.call('PTTL', key) < lease then
+		redis.call('PEXPIRE', key, lease)
+	end
+	return 1
+`)
+
+// RedisConcurrencyLimiter 基于Redis的分布式并发数限制器
+// 每个名额是一个带过期时间的租约，持有期间自动续约，实例崩溃后名额在租约到期后释放。
+// lease必须大于续约可能被阻塞的最长时间(如Redis不可用或进程停顿)，否则租约会在请求执行期间被清理，
+// 此时尝试重新获取名额，名额已被占用时实际并发数会暂时超过limit，并通过日志和指标记录
+type RedisConcurrencyLimiter struct {
+	client  redis.UniversalClient
+	limit   int
+	maxWait time.Duration
+	lease   time.Duration
+	prefix  string
+	metrics metrics.RateLimitMetrics
+	logger  *zap.Logger
+}
+
+// NewRedisConcurrencyLimiter 创建Redis并发数限制器，lease为租约时长(<=0时为30秒)
+// 通过 WithLogger 和 WithMetrics 记录租约丢失
+func NewRedisConcurrencyLimiter(client redis.UniversalClient, limit int, maxWait, lease time.Duration, prefix string, opts ...Option) *RedisConcurrencyLimiter {
+	if lease <= 0 {
+		lease = 30 * time.Second
+	}
+	o := applyOptions(opts)
+	return &RedisConcurrencyLimiter{
+		client:  client,
+		limit:   limit,
+		maxWait: maxWait,
+		lease:   lease,
+		prefix:  prefix,
+		metrics: rateLimitMetrics(o.metrics),
+		logger:  o.logger,
+	}
+}
+
+// Acquire 获取一个并发名额，名额已满时按退避轮询直到超时
+func (r *RedisConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), error) {
+	fullKey := r.getKey(key)
+	id := uuid.NewString()
+
+	var deadline time.Time
+	if r.maxWait > 0 {
+		deadline = time.Now().Add(r.maxWait)
+	}
+	backoff := 10 * time.Millisecond
+	for {
+		acquired, err := r.acquire(ctx, fullKey, id)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, ErrConcurrencyLimited
+		}
+		if wait > backoff {
+			wait = backoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > 200*time.Millisecond {
+			backoff = 200 * time.Millisecond
+		}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go r.renew(fullKey, id, stop, done)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+			defer cancel()
+			r.client.ZRem(releaseCtx, fullKey, id)
+		})
+	}, nil
+}
+
+// acquire 名额未满时加入租约
+func (r *RedisConcurrencyLimiter) acquire(ctx context.Context, key, id string) (bool, error) {
+	acquired, err := concurrencyAcquireScript.Run(ctx, r.client, []string{key},
+		time.Now().UnixMilli(), r.lease.Milliseconds(), r.limit, id).Int()
+	return acquired == 1, err
+}
+
+// renew 每隔lease/3续约一次，直到stop关闭
+// 租约已被清理时重新获取名额，名额已满时在之后的每次续约时重试
+func (r *RedisConcurrencyLimiter) renew(key, id string, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	interval := r.lease / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lost := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if lost {
+			if reacquired, err := r.acquire(ctx, key, id); err == nil && reacquired {
+				lost = false
+				r.logger.Info("Concurrency lease re-acquired", zap.String("key", key))
+			}
+			cancel()
+			continue
+		}
+
+		renewed, err := concurrencyRenewScript.Run(ctx, r.client, []string{key},
+			time.Now().Add(r.lease).UnixMilli(), r.lease.Milliseconds(), id).Int()
+		if err == nil && renewed == 0 {
+			reacquired, err := r.acquire(ctx, key, id)
+			lost = err != nil || !reacquired
+			r.leaseLost(key, !lost)
+		}
+		cancel()
+	}
+}
+
+// leaseLost 记录请求执行期间租约被清理
+func (r *RedisConcurrencyLimiter) leaseLost(key string, reacquired bool) {
+	if r.metrics != nil {
+		r.metrics.RecordConcurrencyLeaseLost(reacquired)
+	}
+	if reacquired {
+		r.logger.Warn("Concurrency lease expired during request, re-acquired",
+			zap.String("key", key), zap.Duration("lease", r.lease))
+		return
+	}
+	r.logger.Warn("Concurrency lease expired during request, limit may be exceeded until re-acquired",
+		zap.String("key", key), zap.Duration("lease", r.lease))
+}
+
+// getKey 获取完整的key
+func (r *RedisConcurrencyLimiter) getKey(key string) string {
+	return fmt.Sprintf("%s:%s", r.prefix, key)
+}
+
+// ConcurrencyInterceptorConfig 并发数限制拦截器配置
+type ConcurrencyInterceptorConfig struct {
+	Limiter  ConcurrencyLimiter
+	KeyFunc  KeyFunc // 默认按方法限制
+	SkipFunc SkipFunc
+}
+
+// UnaryServerConcurrencyInterceptor 创建限制并发数的一元服务器拦截器
+func UnaryServerConcurrencyInterceptor(config *ConcurrencyInterceptorConfig) grpc.UnaryServerInterceptor {
+	if config.KeyFunc == nil {
+		config.KeyFunc = MethodKeyFunc
+	}
+	if config.SkipFunc == nil {
+		config.SkipFunc = DefaultSkipFunc
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if config.SkipFunc(ctx, info) {
+			return handler(ctx, req)
+		}
+
+		release, err := config.Limiter.Acquire(ctx, config.KeyFunc(ctx, info))
+		if err != nil {
+			if rejected := concurrencyError(ctx, err); rejected != nil {
+				return nil, rejected
+			}
+			// 限流器错误，允许请求通过
+			return handler(ctx, req)
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerConcurrencyInterceptor 创建限制并发数的流服务器拦截器，名额在整个流结束后释放
+func StreamServerConcurrencyInterceptor(config *ConcurrencyInterceptorConfig) grpc.StreamServerInterceptor {
+	if config.KeyFunc == nil {
+		config.KeyFunc = MethodKeyFunc
+	}
+	if config.SkipFunc == nil {
+		config.SkipFunc = DefaultSkipFunc
+	}
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		unaryInfo := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: info.FullMethod,
+		}
+
+		if config.SkipFunc(ctx, unaryInfo) {
+			return handler(srv, ss)
+		}
+
+		release, err := config.Limiter.Acquire(ctx, config.KeyFunc(ctx, unaryInfo))
+		if err != nil {
+			if rejected := concurrencyError(ctx, err); rejected != nil {
+				return rejected
+			}
+			// 限流器错误，允许请求通过
+			return handler(srv, ss)
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
+
+// concurrencyError 将排队超时和上下文结束转换为gRPC错误，其他错误返回nil
+func concurrencyError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrConcurrencyLimited):
+		return status.Error(codes.ResourceExhausted, "too many concurrent requests")
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// runConcurrencySuite 对并发数限制器运行通用测试
+func runConcurrencySuite(t *testing.T, newLimiter func(limit int, maxWait time.Duration) ConcurrencyLimiter) {
+	ctx := context.Background()
+
+	t.Run("Limit", func(t *testing.T) {
+		limiter := newLimiter(2, 0)
+		first, err := limiter.Acquire(ctx, "k")
+		if err != nil {
+			t.Fatalf("Failed to acquire: %v", err)
+		}
+		second, err := limiter.Acquire(ctx, "k")
+		if err != nil {
+			t.Fatalf("Failed to acquire: %v", err)
+		}
+		if _, err := limiter.Acquire(ctx, "k"); !errors.Is(err, ErrConcurrencyLimited) {
+			t.Errorf("Expected ErrConcurrencyLimited, got %v", err)
+		}
+		if release, err := limiter.Acquire(ctx, "other"); err != nil {
+			t.Errorf("Expected other key to be independent, got %v", err)
+		} else {
+			release()
+		}
+
+		first()
+		first() // 重复释放不影响其他持有者
+		third, err := limiter.Acquire(ctx, "k")
+		if err != nil {
+			t.Fatalf("Expected slot after release, got %v", err)
+		}
+		if _, err := limiter.Acquire(ctx, "k"); !errors.Is(err, ErrConcurrencyLimited) {
+			t.Errorf("Expected ErrConcurrencyLimited after double release, got %v", err)
+		}
+		second()
+		third()
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		limiter := newLimiter(1, time.Second)
+		release, err := limiter.Acquire(ctx, "k")
+		if err != nil {
+			t.Fatalf("Failed to acquire: %v", err)
+		}
+		time.AfterFunc(50*time.Millisecond, release)
+
+		start := time.Now()
+		queued, err := limiter.Acquire(ctx, "k")
+		if err != nil {
+			t.Fatalf("Expected queued request to acquire, got %v", err)
+		}
+		if time.Since(start) < 40*time.Millisecond {
+			t.Error("Expected queued request to wait for release")
+		}
+		queued()
+	})
+
+	t.Run("QueueTimeout", func(t *testing.T) {
+		limiter := newLimiter(1, 50*time.Millisecond)
+		release, _ := limiter.Acquire(ctx, "k")
+		defer release()
+
+		if _, err := limiter.Acquire(ctx, "k"); !errors.Is(err, ErrConcurrencyLimited) {
+			t.Errorf("Expected ErrConcurrencyLimited after max wait, got %v", err)
+		}
+
+		limiter = newLimiter(1, time.Minute)
+		held, _ := limiter.Acquire(ctx, "k")
+		defer held()
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := limiter.Acquire(timeout, "k"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context deadline while queued, got %v", err)
+		}
+	})
+}
+
+// TestLocalConcurrencyLimiter 测试进程内并发数限制器
+func TestLocalConcurrencyLimiter(t *testing.T) {
+	runConcurrencySuite(t, func(limit int, maxWait time.Duration) ConcurrencyLimiter {
+		return NewLocalConcurrencyLimiter(limit, maxWait)
+	})
+
+	// 并发调用时同时持有的名额不超过上限，结束后不残留状态
+	limiter := NewLocalConcurrencyLimiter(3, time.Second)
+	var inFlight, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background(), "k")
+			if err != nil {
+				t.Errorf("Failed to acquire: %v", err)
+				return
+			}
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most 3 in-flight calls, got %d", peak.Load())
+	}
+	if len(limiter.slots) != 0 {
+		t.Errorf("Expected no slots left, got %d", len(limiter.slots))
+	}
+}
+
+// TestRedisConcurrencyLimiter 测试Redis并发数限制器
+func TestRedisConcurrencyLimiter(t *testing.T) {
+	client := newTestRedisClient(t)
+	prefix := "test:concurrency:" + uuid.NewString()
+	runConcurrencySuite(t, func(limit int, maxWait time.Duration) ConcurrencyLimiter {
+		return NewRedisConcurrencyLimiter(client, limit, maxWait, time.Second, prefix+":"+uuid.NewString())
+	})
+
+	// 未释放的租约在过期后自动回收，持有期间续约不会过期
+	ctx := context.Background()
+	limiter := NewRedisConcurrencyLimiter(client, 1, 0, 300*time.Millisecond, prefix)
+	held, err := limiter.Acquire(ctx, "lease")
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if _, err := limiter.Acquire(ctx, "lease"); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Errorf("Expected renewed lease to still be held, got %v", err)
+	}
+	held()
+
+	// 模拟崩溃实例留下的租约，到期后名额被回收
+	client.ZAdd(ctx, limiter.getKey("crash"), redis.Z{Score: float64(time.Now().Add(200 * time.Millisecond).UnixMilli()), Member: "crashed"})
+	if _, err := limiter.Acquire(ctx, "crash"); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Errorf("Expected crashed lease to hold the slot, got %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if release, err := limiter.Acquire(ctx, "crash"); err != nil {
+		t.Errorf("Expected slot after lease loss, got %v", err)
+	} else {
+		release()
+	}
+}
+
+// leaseRecorder 记录租约丢失指标
+type leaseRecorder struct {
+	metrics.MetricsCollector
+	metrics.RateLimitMetrics
+	mu   sync.Mutex
+	lost []bool
+}
+
+func (r *leaseRecorder) RecordConcurrencyLeaseLost(reacquired bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lost = append(r.lost, reacquired)
+}
+
+// TestRedisConcurrencyLeaseLost 测试请求执行期间租约被清理时重新获取名额
+func TestRedisConcurrencyLeaseLost(t *testing.T) {
+	client := newTestRedisClient(t)
+	recorder := &leaseRecorder{}
+	limiter := NewRedisConcurrencyLimiter(client, 1, 0, 300*time.Millisecond, "test:concurrency:"+uuid.NewString(), WithMetrics(recorder))
+	ctx := context.Background()
+
+	release, err := limiter.Acquire(ctx, "lost")
+	if err != nil {
+		t.Fatalf("Failed to acquire: %v", err)
+	}
+	defer release()
+
+	// 清理全部租约，模拟续约被阻塞超过租约时长
+	client.Del(ctx, limiter.getKey("lost"))
+	time.Sleep(250 * time.Millisecond)
+
+	recorder.mu.Lock()
+	lost := append([]bool(nil), recorder.lost...)
+	recorder.mu.Unlock()
+	if len(lost) != 1 || !lost[0] {
+		t.Errorf("Expected one re-acquired lease loss recorded, got %v", lost)
+	}
+	if _, err := limiter.Acquire(ctx, "lost"); !errors.Is(err, ErrConcurrencyLimited) {
+		t.Errorf("Expected re-acquired lease to hold the slot, got %v", err)
+	}
+}
+
+// TestConcurrencyInterceptors 测试并发数限制拦截器
+func TestConcurrencyInterceptors(t *testing.T) {
+	limiter := NewLocalConcurrencyLimiter(1, 0)
+	config := &ConcurrencyInterceptorConfig{Limiter: limiter}
+	unary := UnaryServerConcurrencyInterceptor(config)
+	stream := StreamServerConcurrencyInterceptor(config)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Report/Generate"}
+	entered := make(chan struct{})
+	finish := make(chan struct{})
+	go unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(entered)
+		<-finish
+		return nil, nil
+	})
+	<-entered
+
+	_, err := unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted, got %v", err)
+	}
+
+	ss := &fakeServerStream{ctx: context.Background()}
+	err = stream(nil, ss, &grpc.StreamServerInfo{FullMethod: "/pkg.Report/Generate"}, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected stream to be limited by the same key, got %v", err)
+	}
+	close(finish)
+}
+
+// fakeServerStream 测试用ServerStream
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// errCPUUnsupported 当前平台不支持CPU采样
+var errCPUUnsupported = errors.New("ratelimit: cpu sampling is not supported on this platform")
+
+// cpuSampler 定期采样整机CPU使用率
+type cpuSampler struct {
+	usage atomic.Uint64 // float64位模式，取值0~1
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newCPUSampler 创建CPU采样器并启动后台采样，平台不支持时返回错误
+func newCPUSampler(interval time.Duration) (*cpuSampler, error) {
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return nil, err
+	}
+	s := &cpuSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run(interval, idle, total)
+	return s, nil
+}
+
+// Usage 最近一次采样的CPU使用率，取值0~1
+func (s *cpuSampler) Usage() float64 {
+	return math.Float64frombits(s.usage.Load())
+}
+
+// Close 停止采样
+func (s *cpuSampler) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+// run 采样循环
+func (s *cpuSampler) run(interval time.Duration, lastIdle, lastTotal uint64) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		idle, total, err := readCPUTimes()
+		if err != nil || total <= lastTotal {
+			continue
+		}
+		usage := 1 - float64(idle-lastIdle)/float64(total-lastTotal)
+		s.usage.Store(math.Float64bits(math.Max(0, math.Min(1, usage))))
+		lastIdle, lastTotal = idle, total
+	}
+}
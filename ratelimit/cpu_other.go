@@ -0,0 +1,8 @@
+//go:build !linux
+
+package ratelimit
+
+// readCPUTimes 非Linux平台不支持CPU采样
+func readCPUTimes() (idle, total uint64, err error) {
+	return 0, 0, errCPUUnsupported
+}
@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+)
+
+// 限流器出错时的处理策略
+const (
+	FailOpen   = "open"   // 放行请求(默认)
+	FailClosed = "closed" // 拒绝请求
+	FailLocal  = "local"  // 降级为本地内存限流，Redis恢复后自动切回
+)
+
+// Option 限流器选项
+type Option func(*options)
+
+// options 限流器选项
+type options struct {
+	metrics  metrics.MetricsCollector
+	logger   *zap.Logger
+	failover *FailoverConfig
+	// ipKeyFunc 规则中按IP限流时使用的key生成函数
+	ipKeyFunc KeyFunc
+}
+
+// WithMetrics 记录限流器错误，collector需实现 metrics.RateLimitMetrics，否则不记录
+func WithMetrics(collector metrics.MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = collector
+	}
+}
+
+// rateLimitMetrics 返回collector实现的限流指标接口，collector为nil或未实现时返回nil
+func rateLimitMetrics(collector metrics.MetricsCollector) metrics.RateLimitMetrics {
+	m, _ := collector.(metrics.RateLimitMetrics)
+	return m
+}
+
+// WithLogger 设置记录限流器错误和降级切换的日志器
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithFailover Redis限流器出错时降级为本地内存限流器
+func WithFailover(config *FailoverConfig) Option {
+	return func(o *options) {
+		o.failover = config
+	}
+}
+
+// WithIPKeyFunc 设置规则中按IP限流以及user、token规则中匿名请求使用的key生成函数，如NewIPKeyFunc创建的信任代理的函数，默认为DefaultKeyFunc
+func WithIPKeyFunc(keyFunc KeyFunc) Option {
+	return func(o *options) {
+		o.ipKeyFunc = keyFunc
+	}
+}
+
+// applyOptions 应用选项
+func applyOptions(opts []Option) *options {
+	o := &options{logger: zap.NewNop()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.logger == nil {
+		o.logger = zap.NewNop()
+	}
+	if o.ipKeyFunc == nil {
+		o.ipKeyFunc = DefaultKeyFunc
+	}
+	return o
+}
+
+// FailoverConfig 降级配置
+type FailoverConfig struct {
+	Instances     int           `yaml:"instances" json:"instances" mapstructure:"instances"`                // 服务实例数，本地限流器的配额为原配额除以实例数
+	RetryInterval time.Duration `yaml:"retry_interval" json:"retry_interval" mapstructure:"retry_interval"` // 降级后重新尝试Redis的间隔
+}
+
+// DefaultFailoverConfig 默认降级配置
+func DefaultFailoverConfig() *FailoverConfig {
+	return &FailoverConfig{
+		Instances:     1,
+		RetryInterval: 5 * time.Second,
+	}
+}
+
+// FailoverRateLimiter 带降级的限流器
+// 主限流器出错时切换到降级限流器，每隔RetryInterval放行一个请求探测主限流器，探测成功后切回。
+// 调用方取消导致的错误不触发降级。
+type FailoverRateLimiter struct {
+	primary  RateLimiter
+	fallback RateLimiter
+	interval time.Duration
+	metrics  metrics.RateLimitMetrics
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu       sync.Mutex
+	degraded bool
+	probing  bool
+	retryAt  time.Time
+}
+
+// NewFailoverRateLimiter 创建带降级的限流器，config中的Instances不在这里使用，由调用方缩放fallback的配额
+func NewFailoverRateLimiter(primary, fallback RateLimiter, config *FailoverConfig, opts ...Option) *FailoverRateLimiter {
+	if config == nil {
+		config = DefaultFailoverConfig()
+	}
+	interval := config.RetryInterval
+	if interval <= 0 {
+		interval = DefaultFailoverConfig().RetryInterval
+	}
+	o := applyOptions(opts)
+	return &FailoverRateLimiter{
+		primary:  primary,
+		fallback: fallback,
+		interval: interval,
+		metrics:  rateLimitMetrics(o.metrics),
+		logger:   o.logger,
+		now:      time.Now,
+	}
+}
+
+// Degraded 当前是否已降级为本地限流
+func (f *FailoverRateLimiter) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.degraded
+}
+
+// Allow 检查是否允许请求
+func (f *FailoverRateLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return f.AllowN(ctx, key, 1)
+}
+
+// AllowN 检查是否允许N个请求
+func (f *FailoverRateLimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	var allowed bool
+	err := f.do(ctx, func(limiter RateLimiter) (err error) {
+		allowed, err = limiter.AllowN(ctx, key, n)
+		return err
+	})
+	return allowed, err
+}
+
+// Decide 对N个请求作出限流决策
+func (f *FailoverRateLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	var decision *Decision
+	err := f.do(ctx, func(limiter RateLimiter) (err error) {
+		decision, err = limiter.Decide(ctx, key, n)
+		return err
+	})
+	return decision, err
+}
+
+// Reset 重置限制
+func (f *FailoverRateLimiter) Reset(ctx context.Context, key string) error {
+	return f.do(ctx, func(limiter RateLimiter) error {
+		return limiter.Reset(ctx, key)
+	})
+}
+
+// GetRemaining 获取剩余请求数
+func (f *FailoverRateLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
+	var remaining int
+	err := f.do(ctx, func(limiter RateLimiter) (err error) {
+		remaining, err = limiter.GetRemaining(ctx, key)
+		return err
+	})
+	return remaining, err
+}
+
+// do 优先使用主限流器，出错时改用降级限流器
+func (f *FailoverRateLimiter) do(ctx context.Context, fn func(limiter RateLimiter) error) error {
+	if f.usePrimary() {
+		err := fn(f.primary)
+		if err == nil {
+			f.recordSuccess()
+			return nil
+		}
+		if ctx.Err() != nil {
+			f.recordCanceled()
+			return err
+		}
+		f.recordFailure(err)
+	}
+	return fn(f.fallback)
+}
+
+// usePrimary 判断是否使用主限流器，降级期间每个重试间隔只放行一个探测请求
+func (f *FailoverRateLimiter) usePrimary() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.degraded {
+		return true
+	}
+	if f.probing || f.now().Before(f.retryAt) {
+		return false
+	}
+	f.probing = true
+	return true
+}
+
+// recordSuccess 主限流器调用成功，降级中时切回主限流器
+func (f *FailoverRateLimiter) recordSuccess() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.degraded {
+		f.degraded, f.probing = false, false
+		f.logger.Info("Rate limiter recovered, switched back from local fallback")
+	}
+}
+
+// recordCanceled 探测请求被调用方取消，允许下一个请求重新探测
+func (f *FailoverRateLimiter) recordCanceled() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probing = false
+}
+
+// recordFailure 主限流器出错，进入或保持降级状态
+func (f *FailoverRateLimiter) recordFailure(err error) {
+	if f.metrics != nil {
+		f.metrics.RecordRateLimitError(FailLocal)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probing = false
+	f.retryAt = f.now().Add(f.interval)
+	if !f.degraded {
+		f.degraded = true
+		f.logger.Warn("Rate limiter unavailable, falling back to local limiter",
+			zap.Duration("retry_interval", f.interval), zap.Error(err))
+	}
+}
+
+// withFailover 配置了降级时为Redis限流器包装按实例数缩放配额的本地降级限流器
+func withFailover(limiter RateLimiter, algorithm string, limit int, window time.Duration, burst int, o *options) (RateLimiter, error) {
+	if o.failover == nil {
+		return limiter, nil
+	}
+	instances := max(1, o.failover.Instances)
+	local, err := newAlgorithmLimiter(nil, algorithm, scaleLimit(limit, instances), window, scaleLimit(burst, instances), "")
+	if err != nil {
+		return nil, err
+	}
+	return NewFailoverRateLimiter(limiter, local, o.failover, WithMetrics(o.metrics), WithLogger(o.logger)), nil
+}
+
+// scaleLimit 按实例数均分配额，向上取整，未设置(<=0)时保持不变
+func scaleLimit(limit, instances int) int {
+	if limit <= 0 {
+		return limit
+	}
+	return (limit + instances - 1) / instances
+}
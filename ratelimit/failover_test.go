@@ -0,0 +1,233 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/vera-byte/vgo-kit/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyLimiter 可切换为出错状态的限流器
+type flakyLimiter struct {
+	RateLimiter
+	mu     sync.Mutex
+	broken bool
+	calls  int
+}
+
+func (f *flakyLimiter) setBroken(broken bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broken = broken
+}
+
+func (f *flakyLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	f.mu.Lock()
+	f.calls++
+	broken := f.broken
+	f.mu.Unlock()
+	if broken {
+		return nil, errors.New("connection refused")
+	}
+	return f.RateLimiter.Decide(ctx, key, n)
+}
+
+// errorRecorder 记录限流器错误指标
+type errorRecorder struct {
+	metrics.MetricsCollector
+	metrics.RateLimitMetrics
+	mu       sync.Mutex
+	policies []string
+}
+
+func (r *errorRecorder) RecordRateLimitError(policy string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies = append(r.policies, policy)
+}
+
+// TestFailoverRateLimiter 测试出错时降级、按间隔探测并自动恢复
+func TestFailoverRateLimiter(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyLimiter{RateLimiter: NewMemoryRateLimiter(100, time.Minute)}
+	recorder := &errorRecorder{}
+	limiter, err := withFailover(primary, "", 10, time.Minute, 0, applyOptions([]Option{
+		WithFailover(&FailoverConfig{Instances: 3, RetryInterval: time.Second}),
+		WithMetrics(recorder),
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	failover := limiter.(*FailoverRateLimiter)
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	failover.now = clock.Now
+
+	if decision, err := failover.Decide(ctx, "key", 1); err != nil || decision.Limit != 100 {
+		t.Fatalf("Expected primary decision, got %+v, %v", decision, err)
+	}
+
+	primary.setBroken(true)
+	decision, err := failover.Decide(ctx, "key", 1)
+	if err != nil {
+		t.Fatalf("Expected fallback to hide primary error, got %v", err)
+	}
+	// 本地限流器配额为 ceil(10/3)
+	if decision.Limit != 4 {
+		t.Errorf("Expected local limit 4, got %d", decision.Limit)
+	}
+	if !failover.Degraded() {
+		t.Error("Expected limiter to be degraded")
+	}
+	if len(recorder.policies) != 1 || recorder.policies[0] != FailLocal {
+		t.Errorf("Expected one local error recorded, got %v", recorder.policies)
+	}
+
+	// 重试间隔内不再访问主限流器
+	calls := primary.calls
+	for i := 0; i < 3; i++ {
+		failover.Decide(ctx, "key", 1)
+	}
+	if decision, _ := failover.Decide(ctx, "key", 1); decision.Allowed {
+		t.Error("Expected local limiter to deny after 4 requests")
+	}
+	if primary.calls != calls {
+		t.Errorf("Expected no primary calls during retry interval, got %d", primary.calls-calls)
+	}
+
+	// 探测失败时保持降级
+	clock.Advance(time.Second)
+	failover.Decide(ctx, "key", 1)
+	if primary.calls != calls+1 || !failover.Degraded() {
+		t.Errorf("Expected one failed probe, got %d calls, degraded=%v", primary.calls-calls, failover.Degraded())
+	}
+
+	// 探测成功后切回主限流器
+	primary.setBroken(false)
+	clock.Advance(time.Second)
+	if decision, err := failover.Decide(ctx, "key", 1); err != nil || decision.Limit != 100 {
+		t.Fatalf("Expected primary decision after recovery, got %+v, %v", decision, err)
+	}
+	if failover.Degraded() {
+		t.Error("Expected limiter to recover")
+	}
+}
+
+// TestFailoverCanceled 测试调用方取消不触发降级
+func TestFailoverCanceled(t *testing.T) {
+	primary := &flakyLimiter{RateLimiter: NewMemoryRateLimiter(100, time.Minute)}
+	primary.setBroken(true)
+	failover := NewFailoverRateLimiter(primary, NewMemoryRateLimiter(1, time.Minute), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := failover.Decide(ctx, "key", 1); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+	if failover.Degraded() {
+		t.Error("Expected canceled request not to degrade limiter")
+	}
+}
+
+// TestInterceptorFailurePolicy 测试拦截器的失败策略
+func TestInterceptorFailurePolicy(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	primary := &flakyLimiter{RateLimiter: NewMemoryRateLimiter(1, time.Minute)}
+	primary.setBroken(true)
+
+	tests := []struct {
+		policy string
+		code   codes.Code
+	}{
+		{"", codes.OK},
+		{FailOpen, codes.OK},
+		{FailClosed, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		recorder := &errorRecorder{}
+		interceptor := UnaryServerInterceptor(&InterceptorConfig{
+			RateLimiter:   primary,
+			FailurePolicy: tt.policy,
+			Metrics:       recorder,
+		})
+		ctx, _ := newTestServerContext()
+		_, err := interceptor(ctx, nil, info, handler)
+		if status.Code(err) != tt.code {
+			t.Errorf("Policy %q: expected %v, got %v", tt.policy, tt.code, err)
+		}
+		want := tt.policy
+		if want == "" {
+			want = FailOpen
+		}
+		if len(recorder.policies) != 1 || recorder.policies[0] != want {
+			t.Errorf("Policy %q: expected error recorded as %s, got %v", tt.policy, want, recorder.policies)
+		}
+	}
+}
+
+// TestInterceptorShadowFailClosed 测试影子模式下限流器出错时只记录不拒绝
+func TestInterceptorShadowFailClosed(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	primary := &flakyLimiter{RateLimiter: NewMemoryRateLimiter(1, time.Minute)}
+	primary.setBroken(true)
+
+	recorder := &errorRecorder{}
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{
+		RateLimiter:   primary,
+		FailurePolicy: FailClosed,
+		Shadow:        true,
+		Metrics:       recorder,
+	})
+	ctx, _ := newTestServerContext()
+	if resp, err := interceptor(ctx, nil, info, handler); err != nil || resp != "ok" {
+		t.Errorf("Expected shadow mode to pass request through, got %v, %v", resp, err)
+	}
+	if len(recorder.policies) != 1 || recorder.policies[0] != FailClosed {
+		t.Errorf("Expected error recorded as %s, got %v", FailClosed, recorder.policies)
+	}
+}
+
+// TestRateLimitConfigFromViper 测试从配置文件解析失败策略和降级配置
+func TestRateLimitConfigFromViper(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewBufferString(`
+ratelimit:
+  type: redis
+  limit: 10
+  window: "1s"
+  failure_policy: closed
+  shadow: true
+  failover:
+    instances: 4
+    retry_interval: "2s"
+`))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	config := DefaultRateLimitConfig()
+	if err := v.UnmarshalKey("ratelimit", config); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if config.Type != "redis" || config.Limit != 10 || config.Window != time.Second || !config.Enabled {
+		t.Errorf("Unexpected config %+v", config)
+	}
+	if config.FailurePolicy != FailClosed || config.Failover.Instances != 4 || config.Failover.RetryInterval != 2*time.Second {
+		t.Errorf("Unexpected failure policy %q or failover %+v", config.FailurePolicy, config.Failover)
+	}
+
+	interceptorConfig := config.InterceptorConfig(&NoOpRateLimiter{})
+	if interceptorConfig.FailurePolicy != FailClosed || !interceptorConfig.Shadow || interceptorConfig.RateLimiter == nil {
+		t.Errorf("Unexpected interceptor config %+v", interceptorConfig)
+	}
+}
@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/cache"
+)
+
+// gcraScript 通用信元速率算法，只保存理论到达时间(TAT，毫秒)
+var gcraScript = cache.RegisterScript("ratelimit:gcra", `
+	local key = KEYS[1]
+	local emission = tonumber(ARGV[1])
+	local tolerance = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local requested = tonumber(ARGV[4])
+
+	local tat = tonumber(redis.call('GET', key))
+	if tat == nil or tat < now then
+		tat = now
+	end
+
+	local new_tat = tat + emission * requested
+	local diff = now - (new_tat - tolerance)
+
+	if diff < 0 then
+		local retry_after = math.ceil(-diff)
+		if emission * requested > tolerance then
+			retry_after = -1
+		end
+		local remaining = math.max(0, math.floor((tolerance - (tat - now)) / emission))
+		return {0, remaining, retry_after, math.ceil(tat - now)}
+	end
+
+	local reset_after = math.ceil(new_tat - now)
+	if requested > 0 and reset_after > 0 then
+		redis.call('SET', key, tostring(new_tat), 'PX', reset_after)
+	end
+
+	return {1, math.floor(diff / emission), 0, reset_after}
+`)
+
+// gcraParams GCRA参数，emission为每个请求的间隔，tolerance为允许的突发时长(毫秒)
+func gcraParams(limit int, window time.Duration, burst int) (emission, tolerance float64, capacity int) {
+	if burst <= 0 {
+		burst = limit
+	}
+	emission = durationMillis(window) / float64(limit)
+	return emission, emission * float64(burst), burst
+}
+
+// RedisGCRALimiter Redis实现的GCRA限流器，每个key只占用一个字符串
+type RedisGCRALimiter struct {
+	client    redis.UniversalClient
+	emission  float64 // 每个请求的间隔(毫秒)
+	tolerance float64 // 突发容忍时长(毫秒)
+	capacity  int     // 突发容量
+	prefix    string  // key前缀
+}
+
+// NewRedisGCRALimiter 创建Redis GCRA限流器，每个window允许limit个请求，burst为突发容量(<=0时等于limit)
+func NewRedisGCRALimiter(client redis.UniversalClient, limit int, window time.Duration, burst int, prefix string) *RedisGCRALimiter {
+	emission, tolerance, capacity := gcraParams(limit, window, burst)
+	return &RedisGCRALimiter{
+		client:    client,
+		emission:  emission,
+		tolerance: tolerance,
+		capacity:  capacity,
+		prefix:    prefix,
+	}
+}
+
+// Decide 尝试获取n个配额并返回限流决策
+func (r *RedisGCRALimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	raw, err := gcraScript.Run(ctx, r.client, []string{r.getKey(key)},
+		r.emission, r.tolerance, time.Now().UnixMilli(), n).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseDecision(raw, r.capacity)
+}
+
+// Allow 检查是否允许请求
+func (r *RedisGCRALimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return r.AllowN(ctx, key, 1)
+}
+
+// AllowN 检查是否允许N个请求
+func (r *RedisGCRALimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	result, err := r.Decide(ctx, key, n)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Reset 重置指定key的限制
+func (r *RedisGCRALimiter) Reset(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.getKey(key)).Err()
+}
+
+// GetRemaining 获取剩余配额
+func (r *RedisGCRALimiter) GetRemaining(ctx context.Context, key string) (int, error) {
+	result, err := r.Decide(ctx, key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return result.Remaining, nil
+}
+
+// getKey 获取完整的key
+func (r *RedisGCRALimiter) getKey(key string) string {
+	return fmt.Sprintf("%s:%s", r.prefix, key)
+}
+
+// MemoryGCRALimiter 内存实现的GCRA限流器，并发安全
+type MemoryGCRALimiter struct {
+	emission  float64
+	tolerance float64
+	capacity  int
+	now       func() time.Time
+	tats      *memoryStore[float64] // 理论到达时间(毫秒)
+}
+
+// NewMemoryGCRALimiter 创建内存GCRA限流器，参数含义与NewRedisGCRALimiter相同
+func NewMemoryGCRALimiter(limit int, window time.Duration, burst int, opts ...MemoryOption) *MemoryGCRALimiter {
+	emission, tolerance, capacity := gcraParams(limit, window, burst)
+	return &MemoryGCRALimiter{
+		emission:  emission,
+		tolerance: tolerance,
+		capacity:  capacity,
+		now:       time.Now,
+		tats:      newMemoryStore[float64](opts),
+	}
+}
+
+// Decide 尝试获取n个配额并返回限流决策
+func (m *MemoryGCRALimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	now := m.now()
+	nowMs := float64(now.UnixMilli())
+	result := &Decision{Limit: m.capacity}
+
+	m.tats.update(key, now, func(stored float64, ok bool) (float64, time.Time) {
+		tat := stored
+		if !ok || tat < nowMs {
+			tat = nowMs
+		}
+
+		increment := m.emission * float64(n)
+		newTAT := tat + increment
+		diff := nowMs - (newTAT - m.tolerance)
+
+		if diff < 0 {
+			result.RetryAfter = millisDuration(-diff)
+			if increment > m.tolerance {
+				result.RetryAfter = -1
+			}
+			result.Remaining = int(math.Max(0, math.Floor((m.tolerance-(tat-nowMs))/m.emission)))
+			result.ResetAfter = millisDuration(tat - nowMs)
+			return tat, now.Add(result.ResetAfter)
+		}
+
+		result.Allowed = true
+		result.Remaining = int(math.Floor(diff / m.emission))
+		result.ResetAfter = millisDuration(newTAT - nowMs)
+		// TAT不晚于当前时间时状态等同于初始值，不再保存
+		return newTAT, now.Add(result.ResetAfter)
+	})
+	return result, nil
+}
+
+// Allow 检查是否允许请求
+func (m *MemoryGCRALimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return m.AllowN(ctx, key, 1)
+}
+
+// AllowN 检查是否允许N个请求
+func (m *MemoryGCRALimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	result, err := m.Decide(ctx, key, n)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Reset 重置指定key的限制
+func (m *MemoryGCRALimiter) Reset(ctx context.Context, key string) error {
+	m.tats.delete(key)
+	return nil
+}
+
+// GetRemaining 获取剩余配额
+func (m *MemoryGCRALimiter) GetRemaining(ctx context.Context, key string) (int, error) {
+	result, err := m.Decide(ctx, key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return result.Remaining, nil
+}
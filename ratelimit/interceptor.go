@@ -3,73 +3,184 @@ package ratelimit
 import (
 	"context"
 	"fmt"
-	"net"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
+// 限流响应头
+const (
+	HeaderRateLimitLimit     = "x-ratelimit-limit"     // 配额上限
+	HeaderRateLimitRemaining = "x-ratelimit-remaining" // 剩余配额
+	HeaderRateLimitReset     = "x-ratelimit-reset"     // 配额完全恢复所需秒数
+	HeaderRetryAfter         = "retry-after"           // 被拒绝时建议的重试等待秒数
+)
+
+// 限流决策结果，用于指标标签
+const (
+	OutcomeAllowed      = "allowed"       // 放行
+	OutcomeDenied       = "denied"        // 拒绝
+	OutcomeShadowDenied = "shadow_denied" // 影子模式下本应拒绝
+)
+
+// DefaultRuleName 未使用规则集时指标中的规则名称
+const DefaultRuleName = "default"
+
 // InterceptorConfig 拦截器配置
 type InterceptorConfig struct {
-	RateLimiter RateLimiter
-	KeyFunc     KeyFunc
-	SkipFunc    SkipFunc
+	RateLimiter   RateLimiter
+	KeyFunc       KeyFunc
+	SkipFunc      SkipFunc
+	Rules         *RuleSet                 // 设置后按规则集限流，忽略RateLimiter和KeyFunc
+	FailurePolicy string                   // 限流器出错时放行(FailOpen，默认)或拒绝(FailClosed)，本地降级通过WithFailover配置
+	Logger        *zap.Logger              // 记录限流器错误，默认不记录
+	Metrics       metrics.MetricsCollector // 记录限流决策和错误次数，可为nil，未实现 metrics.RateLimitMetrics 时不记录
+	// Shadow 影子模式，只评估和记录限流决策(指标和采样日志)，不拒绝请求也不设置限流响应头，用于上线新的限流配置前观察影响。
+	// 限流器出错时同样只记录，不按FailClosed拒绝请求
+	Shadow bool
+
+	shadowLogger *zap.Logger
+	metrics      metrics.RateLimitMetrics
 }
 
-// KeyFunc 生成限流key的函数
-type KeyFunc func(ctx context.Context, info *grpc.UnaryServerInfo) string
+// setDefaults 设置默认值
+func (c *InterceptorConfig) setDefaults() {
+	if c.KeyFunc == nil {
+		c.KeyFunc = DefaultKeyFunc
+	}
+	if c.SkipFunc == nil {
+		c.SkipFunc = DefaultSkipFunc
+	}
+	if c.FailurePolicy == "" {
+		c.FailurePolicy = FailOpen
+	}
+	if c.Logger == nil {
+		c.Logger = zap.NewNop()
+	}
+	c.metrics = rateLimitMetrics(c.Metrics)
+	// 影子模式下每秒最多记录前10条本应拒绝的请求，之后每100条记录1条
+	c.shadowLogger = c.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, 10, 100)
+	}))
+}
 
-// SkipFunc 判断是否跳过限流的函数
-type SkipFunc func(ctx context.Context, info *grpc.UnaryServerInfo) bool
+// decide 对请求作出限流决策，返回作出决策的规则名称和限流key
+func (c *InterceptorConfig) decide(ctx context.Context, info *grpc.UnaryServerInfo) (decision *Decision, rule, key string, err error) {
+	if c.Rules != nil {
+		return c.Rules.decide(ctx, info)
+	}
+	key = c.KeyFunc(ctx, info)
+	decision, err = c.RateLimiter.Decide(ctx, key, 1)
+	return decision, DefaultRuleName, key, err
+}
 
-// DefaultKeyFunc 默认的key生成函数（基于IP地址）
-func DefaultKeyFunc(ctx context.Context, info *grpc.UnaryServerInfo) string {
-	// 尝试从peer获取客户端IP
-	if p, ok := peer.FromContext(ctx); ok {
-		if addr, ok := p.Addr.(*net.TCPAddr); ok {
-			return fmt.Sprintf("ip:%s", addr.IP.String())
-		}
+// check 作出限流决策并记录指标，返回错误时拒绝请求。
+// 返回的决策用于设置响应头，限流器出错或影子模式下为nil
+func (c *InterceptorConfig) check(ctx context.Context, info *grpc.UnaryServerInfo) (*Decision, error) {
+	decision, rule, key, err := c.decide(ctx, info)
+	if err != nil {
+		// 限流器错误，按失败策略拒绝或允许请求通过
+		return nil, c.handleError(ctx, info, err)
+	}
+	// 没有匹配的规则
+	if decision.Limit <= 0 && decision.Allowed {
+		return nil, nil
 	}
 
-	// 尝试从metadata获取真实IP（通过代理时）
-	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if xForwardedFor := md.Get("x-forwarded-for"); len(xForwardedFor) > 0 {
-			// 取第一个IP（原始客户端IP）
-			ips := strings.Split(xForwardedFor[0], ",")
-			if len(ips) > 0 {
-				return fmt.Sprintf("ip:%s", strings.TrimSpace(ips[0]))
-			}
-		}
-		if xRealIP := md.Get("x-real-ip"); len(xRealIP) > 0 {
-			return fmt.Sprintf("ip:%s", xRealIP[0])
+	outcome := OutcomeAllowed
+	if !decision.Allowed {
+		outcome = OutcomeDenied
+		if c.Shadow {
+			outcome = OutcomeShadowDenied
+			c.shadowLogger.Info("Rate limit would reject request",
+				zap.String("method", info.FullMethod),
+				zap.String("rule", rule),
+				zap.String("key", key),
+				zap.Int("limit", decision.Limit),
+				zap.Duration("retry_after", decision.RetryAfter))
 		}
 	}
+	if c.metrics != nil {
+		c.metrics.RecordRateLimitDecision(rule, outcome)
+	}
 
-	return "unknown"
+	if c.Shadow {
+		return nil, nil
+	}
+	return decision, nil
 }
 
-// UserKeyFunc 基于用户ID的key生成函数
-func UserKeyFunc(ctx context.Context, info *grpc.UnaryServerInfo) string {
-	// 尝试从metadata获取用户ID
-	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if userID := md.Get("user-id"); len(userID) > 0 {
-			return fmt.Sprintf("user:%s", userID[0])
+// CheckFunc 返回按config作出限流决策的函数，供HTTP等非gRPC服务复用拦截器的配置和逻辑(规则、失败策略、影子模式和指标)。
+// 返回错误时应拒绝请求，错误为携带RetryInfo的ResourceExhausted或按失败策略返回的Unavailable；
+// 返回的决策非nil时应设置限流响应头
+func CheckFunc(config *InterceptorConfig) func(ctx context.Context, info *grpc.UnaryServerInfo) (*Decision, error) {
+	config.setDefaults()
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) (*Decision, error) {
+		decision, err := config.check(ctx, info)
+		if err != nil {
+			return nil, err
 		}
-		if authorization := md.Get("authorization"); len(authorization) > 0 {
-			// 这里可以解析JWT token获取用户ID
-			// 为简化，直接使用token的hash
-			return fmt.Sprintf("token:%s", authorization[0][:min(len(authorization[0]), 32)])
+		if decision != nil && !decision.Allowed {
+			return decision, rateLimitError(decision)
 		}
+		return decision, nil
+	}
+}
+
+// handleError 按失败策略处理限流器错误，返回nil时放行请求
+func (c *InterceptorConfig) handleError(ctx context.Context, info *grpc.UnaryServerInfo, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if c.metrics != nil {
+		c.metrics.RecordRateLimitError(c.FailurePolicy)
 	}
+	c.Logger.Warn("Rate limiter error",
+		zap.String("method", info.FullMethod),
+		zap.String("policy", c.FailurePolicy),
+		zap.Bool("shadow", c.Shadow),
+		zap.Error(err))
+	// 影子模式下不因限流器错误拒绝请求
+	if c.FailurePolicy == FailClosed && !c.Shadow {
+		return status.Error(codes.Unavailable, "rate limiter unavailable")
+	}
+	return nil
+}
 
-	// 回退到IP限流
-	return DefaultKeyFunc(ctx, info)
+// KeyFunc 生成限流key的函数
+type KeyFunc func(ctx context.Context, info *grpc.UnaryServerInfo) string
+
+// SkipFunc 判断是否跳过限流的函数
+type SkipFunc func(ctx context.Context, info *grpc.UnaryServerInfo) bool
+
+// DefaultKeyFunc 默认的key生成函数（基于连接对端IP，IPv6按/64聚合）
+// 不读取X-Forwarded-For和X-Real-IP，部署在代理后面时使用NewIPKeyFunc配置可信代理
+func DefaultKeyFunc(ctx context.Context, info *grpc.UnaryServerInfo) string {
+	return ipKey(peerIP(ctx), DefaultIPv6PrefixLength)
+}
+
+// UserKeyFunc 基于用户的key生成函数
+// 优先使用认证拦截器通过WithPrincipal写入的主体，其次使用authorization令牌的哈希，都没有时按DefaultKeyFunc回退到IP限流。
+// 不再读取客户端可任意设置的user-id元数据，原先依赖它的服务需要在认证拦截器中调用WithPrincipal
+func UserKeyFunc(ctx context.Context, info *grpc.UnaryServerInfo) string {
+	return defaultUserKeyFunc(ctx, info)
 }
 
+// defaultUserKeyFunc 匿名请求按DefaultKeyFunc限流的UserKeyFunc
+var defaultUserKeyFunc = NewUserKeyFunc(DefaultKeyFunc)
+
 // MethodKeyFunc 基于方法的key生成函数
 func MethodKeyFunc(ctx context.Context, info *grpc.UnaryServerInfo) string {
 	return fmt.Sprintf("method:%s", info.FullMethod)
@@ -109,12 +220,7 @@ func WhitelistSkipFunc(methods []string) SkipFunc {
 
 // UnaryServerInterceptor 创建一元服务器拦截器
 func UnaryServerInterceptor(config *InterceptorConfig) grpc.UnaryServerInterceptor {
-	if config.KeyFunc == nil {
-		config.KeyFunc = DefaultKeyFunc
-	}
-	if config.SkipFunc == nil {
-		config.SkipFunc = DefaultSkipFunc
-	}
+	config.setDefaults()
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		// 检查是否跳过限流
@@ -122,20 +228,25 @@ func UnaryServerInterceptor(config *InterceptorConfig) grpc.UnaryServerIntercept
 			return handler(ctx, req)
 		}
 
-		// 生成限流key
-		key := config.KeyFunc(ctx, info)
-
 		// 检查是否允许请求
-		allowed, err := config.RateLimiter.Allow(ctx, key)
+		decision, err := config.check(ctx, info)
 		if err != nil {
-			// 限流器错误，记录日志但允许请求通过
-			// 这里可以添加日志记录
+			return nil, err
+		}
+		if decision == nil {
 			return handler(ctx, req)
 		}
 
-		if !allowed {
-			// 请求被限流
-			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for key: %s", key)
+		md := decisionMetadata(decision)
+		if md != nil {
+			grpc.SetHeader(ctx, md)
+		}
+		if !decision.Allowed {
+			// 请求被限流，trailer中同样携带限流信息
+			if md != nil {
+				grpc.SetTrailer(ctx, md)
+			}
+			return nil, rateLimitError(decision)
 		}
 
 		// 允许请求通过
@@ -145,12 +256,7 @@ func UnaryServerInterceptor(config *InterceptorConfig) grpc.UnaryServerIntercept
 
 // StreamServerInterceptor 创建流服务器拦截器
 func StreamServerInterceptor(config *InterceptorConfig) grpc.StreamServerInterceptor {
-	if config.KeyFunc == nil {
-		config.KeyFunc = DefaultKeyFunc
-	}
-	if config.SkipFunc == nil {
-		config.SkipFunc = DefaultSkipFunc
-	}
+	config.setDefaults()
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		ctx := ss.Context()
@@ -166,19 +272,25 @@ func StreamServerInterceptor(config *InterceptorConfig) grpc.StreamServerInterce
 			return handler(srv, ss)
 		}
 
-		// 生成限流key
-		key := config.KeyFunc(ctx, unaryInfo)
-
 		// 检查是否允许请求
-		allowed, err := config.RateLimiter.Allow(ctx, key)
+		decision, err := config.check(ctx, unaryInfo)
 		if err != nil {
-			// 限流器错误，记录日志但允许请求通过
+			return err
+		}
+		if decision == nil {
 			return handler(srv, ss)
 		}
 
-		if !allowed {
-			// 请求被限流
-			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded for key: %s", key)
+		md := decisionMetadata(decision)
+		if md != nil {
+			ss.SetHeader(md)
+		}
+		if !decision.Allowed {
+			// 请求被限流，trailer中同样携带限流信息
+			if md != nil {
+				ss.SetTrailer(md)
+			}
+			return rateLimitError(decision)
 		}
 
 		// 允许请求通过
@@ -186,10 +298,45 @@ func StreamServerInterceptor(config *InterceptorConfig) grpc.StreamServerInterce
 	}
 }
 
-// min 辅助函数
-func min(a, b int) int {
-	if a < b {
-		return a
+// decisionMetadata 将限流决策转换为响应头，未限流(Limit为0)时返回nil
+func decisionMetadata(decision *Decision) metadata.MD {
+	if decision.Limit <= 0 {
+		return nil
+	}
+	md := metadata.Pairs(
+		HeaderRateLimitLimit, strconv.Itoa(decision.Limit),
+		HeaderRateLimitRemaining, strconv.Itoa(decision.Remaining),
+		HeaderRateLimitReset, strconv.FormatInt(ceilSeconds(decision.ResetAfter), 10),
+	)
+	if !decision.Allowed && decision.RetryAfter > 0 {
+		md.Set(HeaderRetryAfter, strconv.FormatInt(ceilSeconds(decision.RetryAfter), 10))
+	}
+	return md
+}
+
+// rateLimitError 构造携带RetryInfo和QuotaFailure详情的ResourceExhausted错误
+// 错误信息中不包含限流key，避免泄露客户端IP或令牌
+func rateLimitError(decision *Decision) error {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+
+	var details []protoadapt.MessageV1
+	if decision.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(decision.RetryAfter)})
 	}
-	return b
+	details = append(details, &errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     "requests",
+			Description: fmt.Sprintf("request rate limit of %d exceeded", decision.Limit),
+		}},
+	})
+
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// ceilSeconds 向上取整的秒数
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vera-byte/vgo-kit/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTransportStream 记录服务端设置的header和trailer
+type fakeTransportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *fakeTransportStream) Method() string { return "/test.Service/Method" }
+
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *fakeTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// newTestServerContext 创建带有peer和transport stream的服务端上下文
+func newTestServerContext() (context.Context, *fakeTransportStream) {
+	stream := &fakeTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &fakeAddr{"203.0.113.7:5000"}})
+	return ctx, stream
+}
+
+// fakeAddr 测试用地址
+type fakeAddr struct{ addr string }
+
+func (a *fakeAddr) Network() string { return "tcp" }
+func (a *fakeAddr) String() string  { return a.addr }
+
+// TestUnaryServerInterceptorDecision 测试限流响应头和错误详情
+func TestUnaryServerInterceptorDecision(t *testing.T) {
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{
+		RateLimiter: NewMemoryGCRALimiter(1, time.Minute, 1),
+		KeyFunc:     MethodKeyFunc,
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	ctx, stream := newTestServerContext()
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("Expected first request to pass, got %v", err)
+	}
+	if got := stream.header.Get(HeaderRateLimitLimit); len(got) != 1 || got[0] != "1" {
+		t.Errorf("Expected limit header 1, got %v", got)
+	}
+	if got := stream.header.Get(HeaderRateLimitRemaining); len(got) != 1 || got[0] != "0" {
+		t.Errorf("Expected remaining header 0, got %v", got)
+	}
+	if got := stream.header.Get(HeaderRetryAfter); len(got) != 0 {
+		t.Errorf("Expected no retry-after header on allowed request, got %v", got)
+	}
+
+	ctx, stream = newTestServerContext()
+	_, err := interceptor(ctx, nil, info, handler)
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("Expected ResourceExhausted, got %v", err)
+	}
+	if strings.Contains(st.Message(), "method:") || strings.Contains(st.Message(), "203.0.113.7") {
+		t.Errorf("Expected message not to contain the key, got %q", st.Message())
+	}
+	if got := stream.header.Get(HeaderRetryAfter); len(got) != 1 || got[0] != "60" {
+		t.Errorf("Expected retry-after header 60, got %v", got)
+	}
+	if got := stream.trailer.Get(HeaderRetryAfter); len(got) != 1 {
+		t.Errorf("Expected retry-after trailer, got %v", got)
+	}
+
+	var retryInfo *errdetails.RetryInfo
+	var quotaFailure *errdetails.QuotaFailure
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.RetryInfo:
+			retryInfo = d
+		case *errdetails.QuotaFailure:
+			quotaFailure = d
+		}
+	}
+	if retryInfo == nil || retryInfo.RetryDelay.AsDuration() <= 59*time.Second {
+		t.Errorf("Expected RetryInfo with about 60s delay, got %v", retryInfo)
+	}
+	if quotaFailure == nil || len(quotaFailure.Violations) != 1 {
+		t.Errorf("Expected QuotaFailure detail, got %v", quotaFailure)
+	}
+}
+
+// TestUnaryServerInterceptorNoOp 测试未限流时不设置响应头
+func TestUnaryServerInterceptorNoOp(t *testing.T) {
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{RateLimiter: &NoOpRateLimiter{}})
+	ctx, stream := newTestServerContext()
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	if err != nil {
+		t.Fatalf("Expected request to pass, got %v", err)
+	}
+	if len(stream.header) != 0 {
+		t.Errorf("Expected no rate limit headers, got %v", stream.header)
+	}
+}
+
+// decisionRecorder 记录限流决策指标
+type decisionRecorder struct {
+	metrics.MetricsCollector
+	metrics.RateLimitMetrics
+	outcomes []string
+}
+
+func (r *decisionRecorder) RecordRateLimitDecision(rule, outcome string) {
+	r.outcomes = append(r.outcomes, rule+":"+outcome)
+}
+
+// TestUnaryServerInterceptorShadow 测试影子模式只记录不拒绝
+func TestUnaryServerInterceptorShadow(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	recorder := &decisionRecorder{}
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{
+		RateLimiter: NewMemoryRateLimiter(1, time.Minute),
+		KeyFunc:     MethodKeyFunc,
+		Shadow:      true,
+		Logger:      zap.New(core),
+		Metrics:     recorder,
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	for i := 0; i < 2; i++ {
+		ctx, stream := newTestServerContext()
+		if _, err := interceptor(ctx, nil, info, handler); err != nil {
+			t.Fatalf("Expected shadow mode not to reject, got %v", err)
+		}
+		if len(stream.header) != 0 {
+			t.Errorf("Expected no rate limit headers in shadow mode, got %v", stream.header)
+		}
+	}
+
+	want := []string{"default:" + OutcomeAllowed, "default:" + OutcomeShadowDenied}
+	if strings.Join(recorder.outcomes, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected outcomes %v, got %v", want, recorder.outcomes)
+	}
+	entries := logs.FilterMessage("Rate limit would reject request").All()
+	if len(entries) != 1 || entries[0].ContextMap()["key"] != "method:/test.Service/Method" {
+		t.Errorf("Expected one would-reject log with key, got %v", entries)
+	}
+}
+
+// TestUnaryServerInterceptorRuleMetrics 测试按规则记录放行和拒绝
+func TestUnaryServerInterceptorRuleMetrics(t *testing.T) {
+	rules, err := NewRuleSet(nil, "", []RuleConfig{{Name: "per-method", Key: RuleKeyMethod, Limit: 1, Window: time.Minute}})
+	if err != nil {
+		t.Fatalf("Failed to create rules: %v", err)
+	}
+	recorder := &decisionRecorder{}
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{Rules: rules, Metrics: recorder})
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	for i := 0; i < 2; i++ {
+		ctx, _ := newTestServerContext()
+		interceptor(ctx, nil, info, handler)
+	}
+	want := []string{"per-method:" + OutcomeAllowed, "per-method:" + OutcomeDenied}
+	if strings.Join(recorder.outcomes, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected outcomes %v, got %v", want, recorder.outcomes)
+	}
+}
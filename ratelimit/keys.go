@@ -0,0 +1,157 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultIPv6PrefixLength IPv6地址默认按/64聚合，同一子网的地址共享配额
+const DefaultIPv6PrefixLength = 64
+
+// principalContextKey 上下文中已认证主体的键
+type principalContextKey struct{}
+
+// WithPrincipal 将认证拦截器验证通过的主体(如用户ID)写入上下文，供UserKeyFunc使用
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext 获取认证拦截器写入的主体
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// IPKeyConfig 基于客户端IP的key配置
+type IPKeyConfig struct {
+	TrustedProxies   []string `yaml:"trusted_proxies" json:"trusted_proxies" mapstructure:"trusted_proxies"`          // 可信代理的CIDR或IP，只有直连对端是可信代理时才读取X-Forwarded-For和X-Real-IP
+	IPv6PrefixLength int      `yaml:"ipv6_prefix_length" json:"ipv6_prefix_length" mapstructure:"ipv6_prefix_length"` // IPv6地址聚合的前缀长度，默认64，128表示不聚合
+}
+
+// NewIPKeyFunc 创建基于客户端IP的key生成函数
+func NewIPKeyFunc(config *IPKeyConfig) (KeyFunc, error) {
+	if config == nil {
+		config = &IPKeyConfig{}
+	}
+	prefix := config.IPv6PrefixLength
+	if prefix == 0 {
+		prefix = DefaultIPv6PrefixLength
+	}
+	if prefix < 0 || prefix > 128 {
+		return nil, fmt.Errorf("invalid IPv6 prefix length: %d", prefix)
+	}
+	trusted := make([]*net.IPNet, 0, len(config.TrustedProxies))
+	for _, value := range config.TrustedProxies {
+		network, err := parseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		trusted = append(trusted, network)
+	}
+
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) string {
+		return ipKey(ClientIP(ctx, trusted), prefix)
+	}, nil
+}
+
+// ClientIP 获取客户端IP
+// 直连对端属于trusted时，从右向左跳过X-Forwarded-For中的可信代理，第一个不可信的地址为客户端IP；
+// 没有X-Forwarded-For时使用X-Real-IP。对端不可信时忽略这些可被伪造的头部
+func ClientIP(ctx context.Context, trusted []*net.IPNet) net.IP {
+	ip := peerIP(ctx)
+	if ip == nil || !containsIP(trusted, ip) {
+		return ip
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var hops []string
+	for _, value := range md.Get("x-forwarded-for") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) > 0 {
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				// 无法解析的地址之前的内容不可信
+				return ip
+			}
+			ip = hop
+			if !containsIP(trusted, hop) {
+				return ip
+			}
+		}
+		return ip
+	}
+
+	if realIP := md.Get("x-real-ip"); len(realIP) > 0 {
+		if parsed := net.ParseIP(strings.TrimSpace(realIP[0])); parsed != nil {
+			return parsed
+		}
+	}
+	return ip
+}
+
+// containsIP 判断ip是否属于任一网段
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipKey 生成IP维度的key，IPv6地址按前缀聚合
+func ipKey(ip net.IP, prefix int) string {
+	if ip == nil {
+		return "unknown"
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return "ip:" + ip4.String()
+	}
+	if prefix >= 128 {
+		return "ip:" + ip.String()
+	}
+	return fmt.Sprintf("ip:%s/%d", ip.Mask(net.CIDRMask(prefix, 128)).String(), prefix)
+}
+
+// HashKey 对令牌等敏感值做SHA-256哈希，避免原始值出现在Redis key和日志中
+func HashKey(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:16])
+}
+
+// TokenKeyFunc 基于元数据中令牌哈希的key生成函数，元数据不存在时使用fallback(为nil时为DefaultKeyFunc)按IP限流
+func TokenKeyFunc(name string, fallback KeyFunc) KeyFunc {
+	name = strings.ToLower(name)
+	if fallback == nil {
+		fallback = DefaultKeyFunc
+	}
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) string {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(name); len(values) > 0 && values[0] != "" {
+				return "token:" + HashKey(values[0])
+			}
+		}
+		return fallback(ctx, info)
+	}
+}
+
+// NewUserKeyFunc 创建基于用户的key生成函数，匿名请求使用fallback(为nil时为DefaultKeyFunc)按IP限流，
+// 部署在代理后面时传入NewIPKeyFunc创建的函数
+func NewUserKeyFunc(fallback KeyFunc) KeyFunc {
+	tokenKeyFunc := TokenKeyFunc("authorization", fallback)
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) string {
+		if principal, ok := PrincipalFromContext(ctx); ok {
+			return fmt.Sprintf("user:%s", principal)
+		}
+		return tokenKeyFunc(ctx, info)
+	}
+}
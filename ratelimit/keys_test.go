@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// peerContext 创建带有对端地址和元数据的上下文
+func peerContext(addr string, pairs ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 5000},
+	})
+	if len(pairs) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+	}
+	return ctx
+}
+
+// TestIPKeyFunc 测试可信代理和IPv6聚合
+func TestIPKeyFunc(t *testing.T) {
+	keyFunc, err := NewIPKeyFunc(&IPKeyConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}})
+	if err != nil {
+		t.Fatalf("Failed to create key func: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"direct client", peerContext("203.0.113.7"), "ip:203.0.113.7"},
+		{"untrusted peer ignores headers", peerContext("203.0.113.7", "x-forwarded-for", "198.51.100.1"), "ip:203.0.113.7"},
+		{"trusted proxy", peerContext("10.0.0.1", "x-forwarded-for", "198.51.100.1"), "ip:198.51.100.1"},
+		{"spoofed leftmost hop", peerContext("10.0.0.1", "x-forwarded-for", "1.1.1.1, 198.51.100.1, 192.0.2.1"), "ip:198.51.100.1"},
+		{"invalid hop", peerContext("10.0.0.1", "x-forwarded-for", "198.51.100.1, garbage, 10.0.0.2"), "ip:10.0.0.2"},
+		{"real ip", peerContext("10.0.0.1", "x-real-ip", "198.51.100.2"), "ip:198.51.100.2"},
+		{"ipv6 aggregation", peerContext("2001:db8:1:2:3:4:5:6"), "ip:2001:db8:1:2::/64"},
+		{"no peer", context.Background(), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := keyFunc(tt.ctx, info); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	// 默认key函数不信任任何代理
+	if got := DefaultKeyFunc(peerContext("10.0.0.1", "x-forwarded-for", "198.51.100.1"), info); got != "ip:10.0.0.1" {
+		t.Errorf("Expected DefaultKeyFunc to ignore X-Forwarded-For, got %s", got)
+	}
+
+	if _, err := NewIPKeyFunc(&IPKeyConfig{TrustedProxies: []string{"not-an-ip"}}); err == nil {
+		t.Error("Expected error for invalid trusted proxy")
+	}
+}
+
+// TestUserKeyFunc 测试优先使用认证主体，令牌只以哈希形式出现在key中
+func TestUserKeyFunc(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	token := "Bearer eyJhbGciOiJIUzI1NiJ9.secret-payload"
+	ctx := peerContext("203.0.113.7", "authorization", token)
+
+	key := UserKeyFunc(ctx, info)
+	if !strings.HasPrefix(key, "token:") || strings.Contains(key, "eyJ") {
+		t.Errorf("Expected hashed token key, got %s", key)
+	}
+	if key != UserKeyFunc(peerContext("198.51.100.1", "authorization", token), info) {
+		t.Error("Expected same token to map to the same key")
+	}
+
+	if got := UserKeyFunc(WithPrincipal(ctx, "user-42"), info); got != "user:user-42" {
+		t.Errorf("Expected principal key, got %s", got)
+	}
+	if got := UserKeyFunc(peerContext("203.0.113.7"), info); got != "ip:203.0.113.7" {
+		t.Errorf("Expected fallback to IP, got %s", got)
+	}
+}
+
+// TestRuleKeysUseIPKeyFunc 测试user和token规则的匿名请求使用WithIPKeyFunc配置的IP key
+func TestRuleKeysUseIPKeyFunc(t *testing.T) {
+	ipKeyFunc, err := NewIPKeyFunc(&IPKeyConfig{TrustedProxies: []string{"10.0.0.0/8"}})
+	if err != nil {
+		t.Fatalf("Failed to create key func: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	ctx := peerContext("10.0.0.1", "x-forwarded-for", "198.51.100.1")
+
+	for _, key := range []string{RuleKeyUser, RuleKeyToken + "x-api-key"} {
+		rules, err := NewRuleSet(nil, "", []RuleConfig{{Name: "r", Key: key, Limit: 10, Window: time.Minute}}, WithIPKeyFunc(ipKeyFunc))
+		if err != nil {
+			t.Fatalf("Failed to create rules: %v", err)
+		}
+		if _, _, got, _ := rules.decide(ctx, info); got != "ip:198.51.100.1" {
+			t.Errorf("Rule key %s: expected anonymous request keyed by client IP, got %s", key, got)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// 内存限流器默认参数
+const (
+	DefaultMemoryShards  = 32     // 默认分片数
+	DefaultMemoryMaxKeys = 100000 // 默认最多保存的key数量
+)
+
+// sweepBatch 每次操作最多顺带清理的过期key数量
+const sweepBatch = 4
+
+// MemoryOption 内存限流器选项
+type MemoryOption func(*memoryOptions)
+
+// memoryOptions 内存限流器选项
+type memoryOptions struct {
+	shards  int
+	maxKeys int
+}
+
+// WithShards 设置分片数，分片越多锁竞争越小
+func WithShards(shards int) MemoryOption {
+	return func(o *memoryOptions) {
+		o.shards = shards
+	}
+}
+
+// WithMaxKeys 设置最多保存的key数量，超出时淘汰最久未访问的key（被淘汰的key限额重新计算）
+func WithMaxKeys(maxKeys int) MemoryOption {
+	return func(o *memoryOptions) {
+		o.maxKeys = maxKeys
+	}
+}
+
+// memoryEntry 限流状态
+type memoryEntry[V any] struct {
+	key      string
+	value    V
+	expireAt time.Time // 状态恢复为初始值的时间，之后可以直接删除
+}
+
+// memoryShard 带锁的分片，按访问顺序维护LRU链表
+type memoryShard[V any] struct {
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	lru     *list.List
+	maxKeys int
+}
+
+// memoryStore 分片的内存限流状态存储，空闲key在状态恢复初始值后被清理
+type memoryStore[V any] struct {
+	seed   maphash.Seed
+	shards []*memoryShard[V]
+}
+
+// newMemoryStore 创建分片存储
+func newMemoryStore[V any](opts []MemoryOption) *memoryStore[V] {
+	options := memoryOptions{shards: DefaultMemoryShards, maxKeys: DefaultMemoryMaxKeys}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.shards <= 0 {
+		options.shards = DefaultMemoryShards
+	}
+	if options.maxKeys <= 0 {
+		options.maxKeys = DefaultMemoryMaxKeys
+	}
+	if options.shards > options.maxKeys {
+		options.shards = options.maxKeys
+	}
+
+	s := &memoryStore[V]{
+		seed:   maphash.MakeSeed(),
+		shards: make([]*memoryShard[V], options.shards),
+	}
+	// 每个分片的容量向上取整，总容量不少于maxKeys
+	perShard := (options.maxKeys + options.shards - 1) / options.shards
+	for i := range s.shards {
+		s.shards[i] = &memoryShard[V]{
+			items:   make(map[string]*list.Element),
+			lru:     list.New(),
+			maxKeys: perShard,
+		}
+	}
+	return s
+}
+
+// shard 获取key所在分片
+func (s *memoryStore[V]) shard(key string) *memoryShard[V] {
+	return s.shards[maphash.String(s.seed, key)%uint64(len(s.shards))]
+}
+
+// update 在分片锁内读取并更新key的状态。
+// fn 接收当前状态（已过期或不存在时ok为false），返回新状态和过期时间；过期时间不晚于now时删除key
+func (s *memoryStore[V]) update(key string, now time.Time, fn func(value V, ok bool) (V, time.Time)) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	var (
+		current V
+		ok      bool
+	)
+	element, exists := shard.items[key]
+	if exists {
+		entry := element.Value.(*memoryEntry[V])
+		if entry.expireAt.After(now) {
+			current, ok = entry.value, true
+		}
+	}
+
+	value, expireAt := fn(current, ok)
+	switch {
+	case !expireAt.After(now):
+		if exists {
+			shard.remove(element)
+		}
+	case exists:
+		entry := element.Value.(*memoryEntry[V])
+		entry.value, entry.expireAt = value, expireAt
+		shard.lru.MoveToFront(element)
+	default:
+		shard.items[key] = shard.lru.PushFront(&memoryEntry[V]{key: key, value: value, expireAt: expireAt})
+		for shard.lru.Len() > shard.maxKeys {
+			shard.remove(shard.lru.Back())
+		}
+	}
+
+	shard.sweep(now)
+}
+
+// delete 删除key
+func (s *memoryStore[V]) delete(key string) {
+	shard := s.shard(key)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if element, ok := shard.items[key]; ok {
+		shard.remove(element)
+	}
+}
+
+// len 当前保存的key数量
+func (s *memoryStore[V]) len() int {
+	n := 0
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		n += shard.lru.Len()
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+// remove 删除元素，调用方需持有锁
+func (sh *memoryShard[V]) remove(element *list.Element) {
+	sh.lru.Remove(element)
+	delete(sh.items, element.Value.(*memoryEntry[V]).key)
+}
+
+// sweep 从LRU尾部清理少量已过期的key，空闲key随后续访问逐步回收，无需后台协程
+func (sh *memoryShard[V]) sweep(now time.Time) {
+	for i := 0; i < sweepBatch; i++ {
+		element := sh.lru.Back()
+		if element == nil || element.Value.(*memoryEntry[V]).expireAt.After(now) {
+			return
+		}
+		sh.remove(element)
+	}
+}
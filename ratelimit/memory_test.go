@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMemoryLimitersConcurrent 测试并发调用时允许的请求数准确，需配合 -race 运行
+func TestMemoryLimitersConcurrent(t *testing.T) {
+	limiters := map[string]RateLimiter{
+		AlgorithmSlidingWindow: NewMemoryRateLimiter(100, time.Hour),
+		AlgorithmTokenBucket:   NewMemoryTokenBucketLimiter(100, time.Hour, 0),
+		AlgorithmGCRA:          NewMemoryGCRALimiter(100, time.Hour, 0),
+	}
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var allowed atomic.Int64
+			var wg sync.WaitGroup
+			for g := 0; g < 16; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 50; i++ {
+						// 共享key和各自独立的key同时访问
+						if ok, _ := limiter.Allow(ctx, "shared"); ok {
+							allowed.Add(1)
+						}
+						limiter.Allow(ctx, fmt.Sprintf("own:%d:%d", g, i))
+						limiter.GetRemaining(ctx, "shared")
+						if i%10 == 0 {
+							limiter.Reset(ctx, fmt.Sprintf("own:%d:%d", g, i))
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			if allowed.Load() != 100 {
+				t.Errorf("Expected exactly 100 allowed requests, got %d", allowed.Load())
+			}
+		})
+	}
+}
+
+// TestMemoryStoreMaxKeys 测试超过容量时淘汰最久未访问的key
+func TestMemoryStoreMaxKeys(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryRateLimiter(1, time.Hour, WithShards(1), WithMaxKeys(3))
+
+	for _, key := range []string{"a", "b", "c"} {
+		limiter.Allow(ctx, key)
+	}
+	// 访问a使其成为最近使用的key，随后写入d淘汰b
+	limiter.GetRemaining(ctx, "a")
+	limiter.Allow(ctx, "d")
+
+	if n := limiter.requests.len(); n != 3 {
+		t.Errorf("Expected 3 keys, got %d", n)
+	}
+	if allowed, _ := limiter.Allow(ctx, "a"); allowed {
+		t.Error("Expected key a to still be limited")
+	}
+	if allowed, _ := limiter.Allow(ctx, "b"); !allowed {
+		t.Error("Expected evicted key b to be allowed again")
+	}
+
+	limiter = NewMemoryRateLimiter(1, time.Hour, WithMaxKeys(1000))
+	for i := 0; i < 5000; i++ {
+		limiter.Allow(ctx, fmt.Sprintf("ip:%d", i))
+	}
+	if n := limiter.requests.len(); n > 1000+DefaultMemoryShards {
+		t.Errorf("Expected at most about 1000 keys, got %d", n)
+	}
+}
+
+// TestMemoryStoreIdleEviction 测试状态恢复初始值后的空闲key被回收
+func TestMemoryStoreIdleEviction(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+
+	sliding := NewMemoryRateLimiter(10, time.Second, WithShards(1))
+	sliding.now = clock.Now
+	bucket := NewMemoryTokenBucketLimiter(10, time.Second, 0, WithShards(1))
+	bucket.now = clock.Now
+	gcra := NewMemoryGCRALimiter(10, time.Second, 0, WithShards(1))
+	gcra.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		key := fmt.Sprintf("idle:%d", i)
+		sliding.Allow(ctx, key)
+		bucket.Allow(ctx, key)
+		gcra.Allow(ctx, key)
+	}
+
+	clock.Advance(2 * time.Second)
+	sliding.Allow(ctx, "active")
+	bucket.Allow(ctx, "active")
+	gcra.Allow(ctx, "active")
+
+	stores := map[string]int{
+		AlgorithmSlidingWindow: sliding.requests.len(),
+		AlgorithmTokenBucket:   bucket.buckets.len(),
+		AlgorithmGCRA:          gcra.tats.len(),
+	}
+	for name, n := range stores {
+		if n != 1 {
+			t.Errorf("%s: expected idle keys to be evicted leaving 1 key, got %d", name, n)
+		}
+	}
+
+	// 未被限流的读取不会产生状态
+	if remaining, _ := bucket.GetRemaining(ctx, "unknown"); remaining != 10 {
+		t.Errorf("Expected 10 remaining, got %d", remaining)
+	}
+	if n := bucket.buckets.len(); n != 1 {
+		t.Errorf("Expected read of unknown key not to be stored, got %d keys", n)
+	}
+}
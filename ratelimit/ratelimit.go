@@ -3,9 +3,12 @@ package ratelimit
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/cache"
 )
 
 // RateLimiter 速率限制器接口
@@ -18,18 +21,138 @@ type RateLimiter interface {
 	Reset(ctx context.Context, key string) error
 	// GetRemaining 获取剩余请求数
 	GetRemaining(ctx context.Context, key string) (int, error)
+	// Decide 尝试获取n个配额并返回限流决策
+	Decide(ctx context.Context, key string, n int) (*Decision, error)
 }
 
+// 限流算法
+const (
+	AlgorithmSlidingWindow = "sliding_window" // 滑动日志，每个请求一条记录
+	AlgorithmTokenBucket   = "token_bucket"   // 令牌桶
+	AlgorithmGCRA          = "gcra"           // 通用信元速率算法
+)
+
+// Decision 限流决策
+type Decision struct {
+	Allowed    bool          // 是否允许
+	Limit      int           // 配额上限（突发容量），0表示未限流
+	Remaining  int           // 剩余可用配额
+	ResetAfter time.Duration // 配额完全恢复所需的时间
+	RetryAfter time.Duration // 被拒绝时需要等待的时间，允许时为0，请求数超过上限时为-1
+}
+
+// parseDecision 解析脚本返回的 {allowed, remaining, retry_after_ms, reset_after_ms}
+func parseDecision(raw interface{}, limit int) (*Decision, error) {
+	values, ok := raw.([]interface{})
+	if !ok || len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", raw)
+	}
+	ints := make([]int64, len(values))
+	for i, value := range values {
+		if ints[i], ok = value.(int64); !ok {
+			return nil, fmt.Errorf("unexpected rate limit script result: %v", raw)
+		}
+	}
+
+	result := &Decision{
+		Allowed:    ints[0] == 1,
+		Limit:      limit,
+		Remaining:  int(ints[1]),
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+		ResetAfter: time.Duration(ints[3]) * time.Millisecond,
+	}
+	if ints[2] < 0 {
+		result.RetryAfter = -1
+	}
+	return result, nil
+}
+
+// durationMillis 以毫秒表示时长，保留小数
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// millisDuration 将毫秒数向上取整为时长
+func millisDuration(ms float64) time.Duration {
+	return time.Duration(math.Ceil(ms)) * time.Millisecond
+}
+
+// allowScript 滑动窗口计数并在未超限时记录请求，时间单位为毫秒
+// 成员带有每次调用唯一的nonce，同一毫秒内的请求不会互相覆盖
+// 返回 {allowed, remaining, retry_after, reset_after}
+var allowScript = cache.RegisterScript("ratelimit:allow", `
+	local key = KEYS[1]
+	local window_start = ARGV[1]
+	local now = ARGV[2]
+	local limit = tonumber(ARGV[3])
+	local increment = tonumber(ARGV[4])
+	local ttl = tonumber(ARGV[5])
+	local nonce = ARGV[6]
+
+	-- 清理过期的记录
+	redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
+
+	-- 获取当前窗口内的请求数
+	local current = redis.call('ZCARD', key)
+
+	local window = tonumber(now) - tonumber(window_start)
+
+	-- 最新记录移出窗口的时间
+	local function reset_after()
+		local newest = redis.call('ZRANGE', key, -1, -1, 'WITHSCORES')
+		if #newest == 0 then
+			return 0
+		end
+		return tonumber(newest[2]) + window - now
+	end
+
+	-- 检查是否超过限制
+	if current + increment > limit then
+		local retry_after = -1
+		if increment <= limit then
+			-- 等待足够多的最早记录移出窗口
+			local oldest = redis.call('ZRANGE', key, current + increment - limit - 1, current + increment - limit - 1, 'WITHSCORES')
+			retry_after = tonumber(oldest[2]) + window - now
+		end
+		return {0, math.max(0, limit - current), retry_after, reset_after()}
+	end
+
+	-- 添加新的请求记录
+	for i = 1, increment do
+		redis.call('ZADD', key, now, now .. ':' .. nonce .. ':' .. i)
+	end
+
+	-- 设置过期时间
+	redis.call('PEXPIRE', key, ttl)
+
+	return {1, limit - current - increment, 0, reset_after()}
+`)
+
+// remainingScript 清理过期记录并返回剩余请求数
+var remainingScript = cache.RegisterScript("ratelimit:remaining", `
+	local key = KEYS[1]
+	local window_start = ARGV[1]
+	local limit = tonumber(ARGV[2])
+
+	-- 清理过期的记录
+	redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
+
+	-- 获取当前窗口内的请求数
+	local current = redis.call('ZCARD', key)
+
+	return limit - current
+`)
+
 // RedisRateLimiter Redis实现的速率限制器
 type RedisRateLimiter struct {
-	client *redis.Client
+	client redis.UniversalClient
 	limit  int           // 限制数量
 	window time.Duration // 时间窗口
 	prefix string        // key前缀
 }
 
 // NewRedisRateLimiter 创建Redis速率限制器
-func NewRedisRateLimiter(client *redis.Client, limit int, window time.Duration, prefix string) *RedisRateLimiter {
+func NewRedisRateLimiter(client redis.UniversalClient, limit int, window time.Duration, prefix string) *RedisRateLimiter {
 	return &RedisRateLimiter{
 		client: client,
 		limit:  limit,
@@ -45,51 +168,26 @@ func (r *RedisRateLimiter) Allow(ctx context.Context, key string) (bool, error)
 
 // AllowN 检查是否允许N个请求
 func (r *RedisRateLimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
-	fullKey := r.getKey(key)
-	now := time.Now().Unix()
-	windowStart := now - int64(r.window.Seconds())
-
-	// 使用Lua脚本确保原子性
-	luaScript := `
-		local key = KEYS[1]
-		local window_start = ARGV[1]
-		local now = ARGV[2]
-		local limit = tonumber(ARGV[3])
-		local increment = tonumber(ARGV[4])
-		local ttl = tonumber(ARGV[5])
-
-		-- 清理过期的记录
-		redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
-
-		-- 获取当前窗口内的请求数
-		local current = redis.call('ZCARD', key)
-
-		-- 检查是否超过限制
-		if current + increment > limit then
-			return {0, current}
-		end
-
-		-- 添加新的请求记录
-		for i = 1, increment do
-			redis.call('ZADD', key, now, now .. ':' .. i)
-		end
-
-		-- 设置过期时间
-		redis.call('EXPIRE', key, ttl)
-
-		return {1, current + increment}
-	`
-
-	result, err := r.client.Eval(ctx, luaScript, []string{fullKey},
-		windowStart, now, r.limit, n, int(r.window.Seconds())+1).Result()
+	decision, err := r.Decide(ctx, key, n)
 	if err != nil {
 		return false, err
 	}
+	return decision.Allowed, nil
+}
 
-	results := result.([]interface{})
-	allowed := results[0].(int64) == 1
+// Decide 尝试记录n个请求并返回限流决策
+func (r *RedisRateLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	fullKey := r.getKey(key)
+	now := time.Now().UnixMilli()
+	windowStart := now - r.window.Milliseconds()
 
-	return allowed, nil
+	// 使用Lua脚本确保原子性
+	result, err := allowScript.Run(ctx, r.client, []string{fullKey},
+		windowStart, now, r.limit, n, r.window.Milliseconds()+1, uuid.NewString()).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseDecision(result, r.limit)
 }
 
 // Reset 重置指定key的限制
@@ -101,25 +199,11 @@ func (r *RedisRateLimiter) Reset(ctx context.Context, key string) error {
 // GetRemaining 获取剩余请求数
 func (r *RedisRateLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
 	fullKey := r.getKey(key)
-	now := time.Now().Unix()
-	windowStart := now - int64(r.window.Seconds())
+	now := time.Now().UnixMilli()
+	windowStart := now - r.window.Milliseconds()
 
 	// 清理过期记录并获取当前计数
-	luaScript := `
-		local key = KEYS[1]
-		local window_start = ARGV[1]
-		local limit = tonumber(ARGV[2])
-
-		-- 清理过期的记录
-		redis.call('ZREMRANGEBYSCORE', key, 0, window_start)
-
-		-- 获取当前窗口内的请求数
-		local current = redis.call('ZCARD', key)
-
-		return limit - current
-	`
-
-	result, err := r.client.Eval(ctx, luaScript, []string{fullKey}, windowStart, r.limit).Result()
+	result, err := remainingScript.Run(ctx, r.client, []string{fullKey}, windowStart, r.limit).Result()
 	if err != nil {
 		return 0, err
 	}
@@ -137,19 +221,21 @@ func (r *RedisRateLimiter) getKey(key string) string {
 	return fmt.Sprintf("%s:%s", r.prefix, key)
 }
 
-// MemoryRateLimiter 内存实现的速率限制器（用于测试或单机部署）
+// MemoryRateLimiter 内存实现的滑动日志速率限制器（用于测试或单机部署），并发安全
 type MemoryRateLimiter struct {
 	limit    int
 	window   time.Duration
-	requests map[string][]time.Time
+	now      func() time.Time
+	requests *memoryStore[[]time.Time]
 }
 
 // NewMemoryRateLimiter 创建内存速率限制器
-func NewMemoryRateLimiter(limit int, window time.Duration) *MemoryRateLimiter {
+func NewMemoryRateLimiter(limit int, window time.Duration, opts ...MemoryOption) *MemoryRateLimiter {
 	return &MemoryRateLimiter{
 		limit:    limit,
 		window:   window,
-		requests: make(map[string][]time.Time),
+		now:      time.Now,
+		requests: newMemoryStore[[]time.Time](opts),
 	}
 }
 
@@ -160,70 +246,116 @@ func (m *MemoryRateLimiter) Allow(ctx context.Context, key string) (bool, error)
 
 // AllowN 检查是否允许N个请求
 func (m *MemoryRateLimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
-	now := time.Now()
-	windowStart := now.Add(-m.window)
-
-	// 清理过期请求
-	if requests, exists := m.requests[key]; exists {
-		var validRequests []time.Time
-		for _, req := range requests {
-			if req.After(windowStart) {
-				validRequests = append(validRequests, req)
-			}
-		}
-		m.requests[key] = validRequests
-	}
-
-	// 检查是否超过限制
-	currentCount := len(m.requests[key])
-	if currentCount+n > m.limit {
-		return false, nil
+	decision, err := m.Decide(ctx, key, n)
+	if err != nil {
+		return false, err
 	}
+	return decision.Allowed, nil
+}
 
-	// 添加新请求
-	for i := 0; i < n; i++ {
-		m.requests[key] = append(m.requests[key], now)
-	}
+// Decide 尝试记录n个请求并返回限流决策
+func (m *MemoryRateLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	now := m.now()
+	decision := &Decision{Limit: m.limit}
+	m.requests.update(key, now, func(requests []time.Time, _ bool) ([]time.Time, time.Time) {
+		// 清理过期请求
+		requests = pruneRequests(requests, now.Add(-m.window))
+
+		// 检查是否超过限制
+		current := len(requests)
+		if current+n <= m.limit {
+			for i := 0; i < n; i++ {
+				requests = append(requests, now)
+			}
+			decision.Allowed = true
+			decision.Remaining = m.limit - current - n
+		} else {
+			decision.Remaining = max(0, m.limit-current)
+			decision.RetryAfter = -1
+			if n <= m.limit {
+				// 等待足够多的最早请求移出窗口
+				decision.RetryAfter = requests[current+n-m.limit-1].Add(m.window).Sub(now)
+			}
+		}
 
-	return true, nil
+		expireAt := m.expireAt(requests)
+		if !expireAt.IsZero() {
+			decision.ResetAfter = expireAt.Sub(now)
+		}
+		return requests, expireAt
+	})
+	return decision, nil
 }
 
 // Reset 重置指定key的限制
 func (m *MemoryRateLimiter) Reset(ctx context.Context, key string) error {
-	delete(m.requests, key)
+	m.requests.delete(key)
 	return nil
 }
 
 // GetRemaining 获取剩余请求数
 func (m *MemoryRateLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
-	now := time.Now()
-	windowStart := now.Add(-m.window)
-
-	// 清理过期请求
-	if requests, exists := m.requests[key]; exists {
-		var validRequests []time.Time
-		for _, req := range requests {
-			if req.After(windowStart) {
-				validRequests = append(validRequests, req)
-			}
-		}
-		m.requests[key] = validRequests
-		return m.limit - len(validRequests), nil
+	now := m.now()
+	remaining := m.limit
+	m.requests.update(key, now, func(requests []time.Time, _ bool) ([]time.Time, time.Time) {
+		requests = pruneRequests(requests, now.Add(-m.window))
+		remaining = m.limit - len(requests)
+		return requests, m.expireAt(requests)
+	})
+	if remaining < 0 {
+		remaining = 0
 	}
+	return remaining, nil
+}
 
-	return m.limit, nil
+// expireAt 最后一个请求移出窗口的时间
+func (m *MemoryRateLimiter) expireAt(requests []time.Time) time.Time {
+	if len(requests) == 0 {
+		return time.Time{}
+	}
+	return requests[len(requests)-1].Add(m.window)
+}
+
+// pruneRequests 原地删除窗口开始前的请求，requests按时间升序排列
+func pruneRequests(requests []time.Time, windowStart time.Time) []time.Time {
+	i := 0
+	for i < len(requests) && !requests[i].After(windowStart) {
+		i++
+	}
+	if i == 0 {
+		return requests
+	}
+	return requests[:copy(requests, requests[i:])]
 }
 
 // RateLimitConfig 速率限制配置
 type RateLimitConfig struct {
-	Enabled   bool          `yaml:"enabled" json:"enabled"`
-	Type      string        `yaml:"type" json:"type"` // "redis" or "memory"
-	Limit     int           `yaml:"limit" json:"limit"`
-	Window    time.Duration `yaml:"window" json:"window"`
-	Prefix    string        `yaml:"prefix" json:"prefix"`
-	RedisAddr string        `yaml:"redis_addr" json:"redis_addr"`
-	RedisDB   int           `yaml:"redis_db" json:"redis_db"`
-	RedisPass string        `yaml:"redis_pass" json:"redis_pass"`
+	Enabled   bool          `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+	Type      string        `yaml:"type" json:"type" mapstructure:"type"`                // "redis" or "memory"
+	Algorithm string        `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"` // "sliding_window"(默认), "token_bucket" or "gcra"
+	Limit     int           `yaml:"limit" json:"limit" mapstructure:"limit"`
+	Window    time.Duration `yaml:"window" json:"window" mapstructure:"window"`
+	Burst     int           `yaml:"burst" json:"burst" mapstructure:"burst"`          // 突发容量，仅token_bucket和gcra使用，默认等于Limit
+	MaxKeys   int           `yaml:"max_keys" json:"max_keys" mapstructure:"max_keys"` // memory类型最多保存的key数量，默认100000
+	Prefix    string        `yaml:"prefix" json:"prefix" mapstructure:"prefix"`
+	RedisAddr string        `yaml:"redis_addr" json:"redis_addr" mapstructure:"redis_addr"`
+	RedisDB   int           `yaml:"redis_db" json:"redis_db" mapstructure:"redis_db"`
+	RedisPass string        `yaml:"redis_pass" json:"redis_pass" mapstructure:"redis_pass"`
+	// redis类型出错时的处理策略: open(默认), closed, local。
+	// open和closed由拦截器处理（通过 InterceptorConfig 方法传给拦截器），local在创建限流器时包装本地降级限流器
+	FailurePolicy string         `yaml:"failure_policy" json:"failure_policy" mapstructure:"failure_policy"`
+	Failover      FailoverConfig `yaml:"failover" json:"failover" mapstructure:"failover"` // local策略的降级配置
+	// 影子模式，拦截器只记录本应拒绝的请求，不实际拒绝
+	Shadow bool `yaml:"shadow" json:"shadow" mapstructure:"shadow"`
+}
+
+// InterceptorConfig 根据配置创建使用limiter的拦截器配置，带上配置中的失败策略和影子模式
+func (c *RateLimitConfig) InterceptorConfig(limiter RateLimiter) *InterceptorConfig {
+	return &InterceptorConfig{
+		RateLimiter:   limiter,
+		FailurePolicy: c.FailurePolicy,
+		Shadow:        c.Shadow,
+	}
 }
 
 // DefaultRateLimitConfig 默认速率限制配置
@@ -238,7 +370,7 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 }
 
 // NewRateLimiter 根据配置创建速率限制器
-func NewRateLimiter(config *RateLimitConfig) (RateLimiter, error) {
+func NewRateLimiter(config *RateLimitConfig, opts ...Option) (RateLimiter, error) {
 	if !config.Enabled {
 		return &NoOpRateLimiter{}, nil
 	}
@@ -250,14 +382,49 @@ func NewRateLimiter(config *RateLimitConfig) (RateLimiter, error) {
 			DB:       config.RedisDB,
 			Password: config.RedisPass,
 		})
-		return NewRedisRateLimiter(client, config.Limit, config.Window, config.Prefix), nil
+		limiter, err := newAlgorithmLimiter(client, config.Algorithm, config.Limit, config.Window, config.Burst, config.Prefix)
+		if err == nil && config.FailurePolicy == FailLocal {
+			failover := config.Failover
+			opts = append(opts, WithFailover(&failover))
+			limiter, err = withFailover(limiter, config.Algorithm, config.Limit, config.Window, config.Burst, applyOptions(opts))
+		}
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		return limiter, nil
 	case "memory":
-		return NewMemoryRateLimiter(config.Limit, config.Window), nil
+		return newAlgorithmLimiter(nil, config.Algorithm, config.Limit, config.Window, config.Burst, config.Prefix,
+			WithMaxKeys(config.MaxKeys))
 	default:
 		return nil, fmt.Errorf("unsupported rate limiter type: %s", config.Type)
 	}
 }
 
+// newAlgorithmLimiter 按算法创建限流器，client为nil时创建内存实现
+func newAlgorithmLimiter(client redis.UniversalClient, algorithm string, limit int, window time.Duration, burst int,
+	prefix string, opts ...MemoryOption) (RateLimiter, error) {
+	switch algorithm {
+	case "", AlgorithmSlidingWindow:
+		if client == nil {
+			return NewMemoryRateLimiter(limit, window, opts...), nil
+		}
+		return NewRedisRateLimiter(client, limit, window, prefix), nil
+	case AlgorithmTokenBucket:
+		if client == nil {
+			return NewMemoryTokenBucketLimiter(limit, window, burst, opts...), nil
+		}
+		return NewRedisTokenBucketLimiter(client, limit, window, burst, prefix), nil
+	case AlgorithmGCRA:
+		if client == nil {
+			return NewMemoryGCRALimiter(limit, window, burst, opts...), nil
+		}
+		return NewRedisGCRALimiter(client, limit, window, burst, prefix), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limiter algorithm: %s", algorithm)
+	}
+}
+
 // NoOpRateLimiter 无操作速率限制器（禁用时使用）
 type NoOpRateLimiter struct{}
 
@@ -276,3 +443,7 @@ func (noop *NoOpRateLimiter) Reset(ctx context.Context, key string) error {
 func (noop *NoOpRateLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
 	return 999999, nil
 }
+
+func (noop *NoOpRateLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	return &Decision{Allowed: true}, nil
+}
@@ -0,0 +1,216 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisClient 连接本地Redis，不可用时跳过测试，可通过 VGO_TEST_REDIS_ADDR 覆盖地址
+func newTestRedisClient(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	addr := os.Getenv("VGO_TEST_REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr, DialTimeout: 500 * time.Millisecond})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		t.Skipf("redis not available at %s: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// TestMemoryTokenBucket 测试内存令牌桶的突发容量和补充速率
+func TestMemoryTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := NewMemoryTokenBucketLimiter(10, time.Second, 5)
+	limiter.now = clock.Now
+
+	for i := 0; i < 5; i++ {
+		result, _ := limiter.Decide(ctx, "k", 1)
+		if !result.Allowed || result.Remaining != 4-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i, 4-i, result)
+		}
+	}
+
+	result, _ := limiter.Decide(ctx, "k", 1)
+	if result.Allowed {
+		t.Fatal("Expected request beyond burst to be denied")
+	}
+	if result.RetryAfter != 100*time.Millisecond {
+		t.Errorf("Expected retry after 100ms, got %v", result.RetryAfter)
+	}
+	if result.ResetAfter != 500*time.Millisecond {
+		t.Errorf("Expected reset after 500ms, got %v", result.ResetAfter)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if result, _ = limiter.Decide(ctx, "k", 1); !result.Allowed {
+		t.Error("Expected request to be allowed after refill")
+	}
+
+	if result, _ = limiter.Decide(ctx, "k", 6); result.Allowed || result.RetryAfter != -1 {
+		t.Errorf("Expected request larger than burst to never be allowed, got %+v", result)
+	}
+
+	clock.Advance(time.Second)
+	if remaining, _ := limiter.GetRemaining(ctx, "k"); remaining != 5 {
+		t.Errorf("Expected 5 remaining after full refill, got %d", remaining)
+	}
+}
+
+// TestMemoryGCRA 测试内存GCRA的突发容量和重试时间
+func TestMemoryGCRA(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := NewMemoryGCRALimiter(10, time.Second, 3)
+	limiter.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		result, _ := limiter.Decide(ctx, "k", 1)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+
+	result, _ := limiter.Decide(ctx, "k", 1)
+	if result.Allowed {
+		t.Fatal("Expected request beyond burst to be denied")
+	}
+	if result.RetryAfter != 100*time.Millisecond {
+		t.Errorf("Expected retry after 100ms, got %v", result.RetryAfter)
+	}
+	if result.ResetAfter != 300*time.Millisecond {
+		t.Errorf("Expected reset after 300ms, got %v", result.ResetAfter)
+	}
+
+	clock.Advance(50 * time.Millisecond)
+	if result, _ = limiter.Decide(ctx, "k", 1); result.Allowed || result.RetryAfter != 50*time.Millisecond {
+		t.Errorf("Expected retry after 50ms, got %+v", result)
+	}
+
+	clock.Advance(50 * time.Millisecond)
+	if result, _ = limiter.Decide(ctx, "k", 1); !result.Allowed {
+		t.Error("Expected request to be allowed after emission interval")
+	}
+
+	if err := limiter.Reset(ctx, "k"); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if remaining, _ := limiter.GetRemaining(ctx, "k"); remaining != 3 {
+		t.Errorf("Expected 3 remaining after reset, got %d", remaining)
+	}
+}
+
+// TestRedisLimiters 测试Redis令牌桶和GCRA与内存实现行为一致
+func TestRedisLimiters(t *testing.T) {
+	client := newTestRedisClient(t)
+	ctx := context.Background()
+	prefix := "test:ratelimit:" + uuid.NewString()
+
+	limiters := map[string]RateLimiter{
+		AlgorithmTokenBucket: NewRedisTokenBucketLimiter(client, 10, time.Minute, 3, prefix+":tb"),
+		AlgorithmGCRA:        NewRedisGCRALimiter(client, 10, time.Minute, 3, prefix+":gcra"),
+	}
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				result, err := limiter.Decide(ctx, "k", 1)
+				if err != nil {
+					t.Fatalf("Failed to take: %v", err)
+				}
+				if !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+					t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i, 2-i, result)
+				}
+			}
+
+			result, err := limiter.Decide(ctx, "k", 1)
+			if err != nil {
+				t.Fatalf("Failed to take: %v", err)
+			}
+			if result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > 6*time.Second {
+				t.Errorf("Expected denial with retry after about 6s, got %+v", result)
+			}
+
+			if remaining, err := limiter.GetRemaining(ctx, "k"); err != nil || remaining != 0 {
+				t.Errorf("Expected 0 remaining, got %d (%v)", remaining, err)
+			}
+			if err := limiter.Reset(ctx, "k"); err != nil {
+				t.Fatalf("Failed to reset: %v", err)
+			}
+			if allowed, err := limiter.AllowN(ctx, "k", 3); err != nil || !allowed {
+				t.Errorf("Expected burst to be allowed after reset, got %v (%v)", allowed, err)
+			}
+		})
+	}
+}
+
+// TestRedisSlidingWindowSameMillisecond 测试同一时刻的请求不会互相覆盖
+func TestRedisSlidingWindowSameMillisecond(t *testing.T) {
+	client := newTestRedisClient(t)
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(client, 5, time.Minute, "test:ratelimit:"+uuid.NewString())
+
+	for i := 0; i < 5; i++ {
+		if allowed, err := limiter.Allow(ctx, "k"); err != nil || !allowed {
+			t.Fatalf("Request %d: expected allowed, got %v (%v)", i, allowed, err)
+		}
+	}
+	decision, err := limiter.Decide(ctx, "k", 1)
+	if err != nil {
+		t.Fatalf("Failed to decide: %v", err)
+	}
+	if decision.Allowed || decision.Remaining != 0 || decision.Limit != 5 {
+		t.Errorf("Expected sixth request to be denied, got %+v", decision)
+	}
+	if decision.RetryAfter <= 0 || decision.RetryAfter > time.Minute || decision.ResetAfter < decision.RetryAfter {
+		t.Errorf("Unexpected retry/reset: %+v", decision)
+	}
+	if remaining, _ := limiter.GetRemaining(ctx, "k"); remaining != 0 {
+		t.Errorf("Expected 0 remaining, got %d", remaining)
+	}
+	limiter.Reset(ctx, "k")
+}
+
+// TestNewRateLimiterAlgorithm 测试根据配置选择算法
+func TestNewRateLimiterAlgorithm(t *testing.T) {
+	cases := map[string]string{
+		"":                     "*ratelimit.MemoryRateLimiter",
+		AlgorithmSlidingWindow: "*ratelimit.MemoryRateLimiter",
+		AlgorithmTokenBucket:   "*ratelimit.MemoryTokenBucketLimiter",
+		AlgorithmGCRA:          "*ratelimit.MemoryGCRALimiter",
+	}
+	for algorithm, expected := range cases {
+		config := DefaultRateLimitConfig()
+		config.Algorithm = algorithm
+		limiter, err := NewRateLimiter(config)
+		if err != nil {
+			t.Fatalf("Failed to create limiter for %q: %v", algorithm, err)
+		}
+		if got := fmt.Sprintf("%T", limiter); got != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, algorithm, got)
+		}
+	}
+
+	config := DefaultRateLimitConfig()
+	config.Algorithm = "leaky"
+	if _, err := NewRateLimiter(config); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+}
@@ -0,0 +1,351 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// 规则的限流维度
+const (
+	RuleKeyIP       = "ip"        // 按客户端IP
+	RuleKeyUser     = "user"      // 按用户ID或令牌
+	RuleKeyMethod   = "method"    // 按方法
+	RuleKeyGlobal   = "global"    // 所有匹配请求共享一个配额
+	RuleKeyMetadata = "metadata:" // 按元数据值，如 metadata:x-tenant-id
+	RuleKeyToken    = "token:"    // 按元数据值的哈希，用于API key等敏感值，如 token:x-api-key
+)
+
+// RuleConfig 限流规则配置
+// 匹配条件之间为“与”关系，同一条件的多个值之间为“或”关系，未配置的条件视为匹配
+type RuleConfig struct {
+	Name      string              `yaml:"name" json:"name" mapstructure:"name"`                // 规则名称，同时作为限流key的一部分，必须唯一
+	Methods   []string            `yaml:"methods" json:"methods" mapstructure:"methods"`       // 完整方法名glob，如 /pkg.Service/*，"*"匹配所有方法
+	Metadata  map[string][]string `yaml:"metadata" json:"metadata" mapstructure:"metadata"`    // 元数据匹配，如 x-plan-tier: [free]，值为"*"时只要求存在
+	Peers     []string            `yaml:"peers" json:"peers" mapstructure:"peers"`             // 客户端地址CIDR或IP
+	Key       string              `yaml:"key" json:"key" mapstructure:"key"`                   // 限流维度: ip(默认), user, method, global, metadata:<name>, token:<name>
+	Algorithm string              `yaml:"algorithm" json:"algorithm" mapstructure:"algorithm"` // 限流算法，默认sliding_window
+	Limit     int                 `yaml:"limit" json:"limit" mapstructure:"limit"`             // 窗口内允许的请求数
+	Window    time.Duration       `yaml:"window" json:"window" mapstructure:"window"`          // 时间窗口
+	Burst     int                 `yaml:"burst" json:"burst" mapstructure:"burst"`             // 突发容量，仅token_bucket和gcra使用
+}
+
+// rule 编译后的限流规则
+type rule struct {
+	config   RuleConfig
+	methods  []string
+	metadata map[string][]string
+	peers    []*net.IPNet
+	keyFunc  KeyFunc
+	limiter  RateLimiter
+}
+
+// RuleSet 限流规则集，支持热更新
+// 对每个请求依次检查所有匹配的规则（如同时按IP、按用户和全局限流），任一规则拒绝即拒绝请求。
+// 拒绝前已通过的规则会消耗配额，应把配额最紧的规则放在前面。
+type RuleSet struct {
+	client  redis.UniversalClient
+	prefix  string
+	options *options
+
+	mu    sync.Mutex // 串行化Update
+	rules atomic.Pointer[[]*rule]
+}
+
+// NewRuleSet 创建规则集，client为nil时使用内存限流器，WithFailover对每条规则的Redis限流器生效
+func NewRuleSet(client redis.UniversalClient, prefix string, configs []RuleConfig, opts ...Option) (*RuleSet, error) {
+	if prefix == "" {
+		prefix = "ratelimit"
+	}
+	r := &RuleSet{client: client, prefix: prefix, options: applyOptions(opts)}
+	if err := r.Update(configs); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Update 替换规则，配置有误时保留原有规则并返回错误
+// 名称和限流参数都未变化的规则沿用原限流器，已消耗的配额不会被重置
+func (r *RuleSet) Update(configs []RuleConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[string]*rule)
+	if current := r.rules.Load(); current != nil {
+		for _, old := range *current {
+			existing[old.config.Name] = old
+		}
+	}
+
+	rules := make([]*rule, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if config.Name == "" {
+			return fmt.Errorf("rate limit rule name is required")
+		}
+		if names[config.Name] {
+			return fmt.Errorf("duplicate rate limit rule: %s", config.Name)
+		}
+		names[config.Name] = true
+
+		compiled, err := r.compile(config, existing[config.Name])
+		if err != nil {
+			return fmt.Errorf("invalid rate limit rule %s: %w", config.Name, err)
+		}
+		rules = append(rules, compiled)
+	}
+
+	r.rules.Store(&rules)
+	return nil
+}
+
+// compile 编译规则，限流参数未变化时沿用old的限流器
+func (r *RuleSet) compile(config RuleConfig, old *rule) (*rule, error) {
+	if config.Limit <= 0 || config.Window <= 0 {
+		return nil, fmt.Errorf("limit and window must be positive")
+	}
+
+	compiled := &rule{config: config, methods: config.Methods, metadata: make(map[string][]string)}
+	for _, method := range config.Methods {
+		if _, err := path.Match(method, ""); err != nil {
+			return nil, fmt.Errorf("invalid method pattern %q: %w", method, err)
+		}
+	}
+	for name, values := range config.Metadata {
+		compiled.metadata[strings.ToLower(name)] = values
+	}
+	for _, value := range config.Peers {
+		network, err := parseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		compiled.peers = append(compiled.peers, network)
+	}
+
+	switch key := config.Key; {
+	case key == "" || key == RuleKeyIP:
+		compiled.keyFunc = r.options.ipKeyFunc
+	case key == RuleKeyUser:
+		compiled.keyFunc = NewUserKeyFunc(r.options.ipKeyFunc)
+	case key == RuleKeyMethod:
+		compiled.keyFunc = MethodKeyFunc
+	case key == RuleKeyGlobal:
+		compiled.keyFunc = func(ctx context.Context, info *grpc.UnaryServerInfo) string { return RuleKeyGlobal }
+	case strings.HasPrefix(key, RuleKeyMetadata) && len(key) > len(RuleKeyMetadata):
+		compiled.keyFunc = MetadataKeyFunc(key[len(RuleKeyMetadata):])
+	case strings.HasPrefix(key, RuleKeyToken) && len(key) > len(RuleKeyToken):
+		compiled.keyFunc = TokenKeyFunc(key[len(RuleKeyToken):], r.options.ipKeyFunc)
+	default:
+		return nil, fmt.Errorf("unsupported key: %s", key)
+	}
+
+	if old != nil && sameLimit(old.config, config) {
+		compiled.limiter = old.limiter
+		return compiled, nil
+	}
+	limiter, err := newAlgorithmLimiter(r.client, config.Algorithm, config.Limit, config.Window, config.Burst,
+		r.prefix+":rule:"+config.Name)
+	if err == nil && r.client != nil {
+		limiter, err = withFailover(limiter, config.Algorithm, config.Limit, config.Window, config.Burst, r.options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	compiled.limiter = limiter
+	return compiled, nil
+}
+
+// sameLimit 判断两个规则的限流参数是否相同
+func sameLimit(a, b RuleConfig) bool {
+	return a.Key == b.Key && a.Algorithm == b.Algorithm && a.Limit == b.Limit &&
+		a.Window == b.Window && a.Burst == b.Burst
+}
+
+// Decide 依次检查所有匹配的规则，返回第一个拒绝的决策；全部通过时返回剩余配额最少的决策。
+// 没有匹配的规则时返回未限流的决策(Limit为0)，rule为作出决策的规则名称
+func (r *RuleSet) Decide(ctx context.Context, info *grpc.UnaryServerInfo) (decision *Decision, rule string, err error) {
+	decision, rule, _, err = r.decide(ctx, info)
+	return decision, rule, err
+}
+
+// decide 同Decide，同时返回作出决策的限流key
+func (r *RuleSet) decide(ctx context.Context, info *grpc.UnaryServerInfo) (decision *Decision, rule, key string, err error) {
+	rules := r.rules.Load()
+	if rules == nil {
+		return &Decision{Allowed: true}, "", "", nil
+	}
+
+	decision = &Decision{Allowed: true}
+	for _, candidate := range *rules {
+		if !candidate.matches(ctx, info) {
+			continue
+		}
+		candidateKey := candidate.keyFunc(ctx, info)
+		current, err := candidate.limiter.Decide(ctx, candidateKey, 1)
+		if err != nil {
+			return nil, candidate.config.Name, candidateKey, err
+		}
+		if !current.Allowed {
+			return current, candidate.config.Name, candidateKey, nil
+		}
+		if decision.Limit == 0 || current.Remaining < decision.Remaining {
+			decision, rule, key = current, candidate.config.Name, candidateKey
+		}
+	}
+	return decision, rule, key, nil
+}
+
+// matches 判断请求是否匹配规则
+func (r *rule) matches(ctx context.Context, info *grpc.UnaryServerInfo) bool {
+	if len(r.methods) > 0 && !matchMethod(r.methods, info.FullMethod) {
+		return false
+	}
+
+	if len(r.metadata) > 0 {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for name, expected := range r.metadata {
+			if !matchValues(expected, md.Get(name)) {
+				return false
+			}
+		}
+	}
+
+	if len(r.peers) > 0 {
+		ip := peerIP(ctx)
+		if ip == nil {
+			return false
+		}
+		matched := false
+		for _, network := range r.peers {
+			if network.Contains(ip) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMethod 判断方法名是否匹配任一glob
+func matchMethod(patterns []string, method string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValues 判断元数据值是否命中期望值之一，期望值"*"表示存在即可
+func matchValues(expected, actual []string) bool {
+	if len(actual) == 0 {
+		return false
+	}
+	for _, want := range expected {
+		for _, got := range actual {
+			if want == "*" || want == got {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCIDR 解析CIDR，单个IP视为/32或/128
+func parseCIDR(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid peer address: %s", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer CIDR: %s", value)
+	}
+	return network, nil
+}
+
+// peerIP 获取连接对端IP
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	if addr, ok := p.Addr.(*net.TCPAddr); ok {
+		return addr.IP
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	return net.ParseIP(host)
+}
+
+// MetadataKeyFunc 基于元数据值的key生成函数，元数据不存在时为空值
+func MetadataKeyFunc(name string) KeyFunc {
+	name = strings.ToLower(name)
+	return func(ctx context.Context, info *grpc.UnaryServerInfo) string {
+		value := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(name); len(values) > 0 {
+				value = values[0]
+			}
+		}
+		return fmt.Sprintf("%s:%s", name, value)
+	}
+}
+
+// LoadRules 从配置中读取规则列表
+func LoadRules(v *viper.Viper, key string) ([]RuleConfig, error) {
+	var configs []RuleConfig
+	if err := v.UnmarshalKey(key, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit rules: %w", err)
+	}
+	return configs, nil
+}
+
+// Reload 从viper重新读取key下的规则并更新，失败时保留原有规则
+// 多个组件共用同一个viper实例时，应只注册一个OnConfigChange回调，在其中调用各规则集的Reload
+func (r *RuleSet) Reload(v *viper.Viper, key string) error {
+	configs, err := LoadRules(v, key)
+	if err != nil {
+		return err
+	}
+	return r.Update(configs)
+}
+
+// Watch 监听配置文件变化并热更新规则，更新失败时保留原有规则并调用onError(可为nil)
+// 注意：viper每个实例只保留一个OnConfigChange回调，Watch会替换已注册的回调，
+// 对同一实例多次调用Watch也只有最后一次生效。与其他热更新逻辑共用viper实例时请改用Reload
+func (r *RuleSet) Watch(v *viper.Viper, key string, onError func(error)) {
+	v.OnConfigChange(func(fsnotify.Event) {
+		if err := r.Reload(v, key); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+	v.WatchConfig()
+}
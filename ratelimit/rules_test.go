@@ -0,0 +1,264 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ruleContext 创建带有peer地址和元数据的上下文
+func ruleContext(ip string, pairs ...string) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+}
+
+// TestRuleSetMatching 测试方法、元数据和peer匹配条件
+func TestRuleSetMatching(t *testing.T) {
+	rules, err := NewRuleSet(nil, "test", []RuleConfig{
+		{Name: "free-tier", Methods: []string{"/pkg.Search/*"}, Metadata: map[string][]string{"X-Plan-Tier": {"free"}},
+			Key: "metadata:x-tenant-id", Limit: 1, Window: time.Minute},
+		{Name: "internal", Peers: []string{"10.0.0.0/8", "192.168.1.5"}, Limit: 1, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rule set: %v", err)
+	}
+
+	search := &grpc.UnaryServerInfo{FullMethod: "/pkg.Search/Query"}
+	other := &grpc.UnaryServerInfo{FullMethod: "/pkg.Other/Query"}
+	cases := []struct {
+		name string
+		ctx  context.Context
+		info *grpc.UnaryServerInfo
+		rule string
+	}{
+		{"free tier search", ruleContext("203.0.113.1", "x-plan-tier", "free", "x-tenant-id", "a"), search, "free-tier"},
+		{"paid tier search", ruleContext("203.0.113.1", "x-plan-tier", "paid", "x-tenant-id", "a"), search, ""},
+		{"free tier other method", ruleContext("203.0.113.1", "x-plan-tier", "free"), other, ""},
+		{"internal cidr", ruleContext("10.1.2.3"), other, "internal"},
+		{"internal single ip", ruleContext("192.168.1.5"), other, "internal"},
+		{"external peer", ruleContext("192.168.1.6"), other, ""},
+	}
+	for _, c := range cases {
+		decision, rule, err := rules.Decide(c.ctx, c.info)
+		if err != nil {
+			t.Fatalf("%s: failed to decide: %v", c.name, err)
+		}
+		if rule != c.rule || !decision.Allowed {
+			t.Errorf("%s: expected allowed by rule %q, got %q (%+v)", c.name, c.rule, rule, decision)
+		}
+	}
+
+	// 同一租户的第二个请求被拒绝，其他租户不受影响
+	if decision, _, _ := rules.Decide(ruleContext("203.0.113.2", "x-plan-tier", "free", "x-tenant-id", "a"), search); decision.Allowed {
+		t.Error("Expected second request of tenant a to be denied")
+	}
+	if decision, _, _ := rules.Decide(ruleContext("203.0.113.2", "x-plan-tier", "free", "x-tenant-id", "b"), search); !decision.Allowed {
+		t.Error("Expected tenant b to be allowed")
+	}
+}
+
+// TestRuleSetCombination 测试多个规则同时生效
+func TestRuleSetCombination(t *testing.T) {
+	rules, err := NewRuleSet(nil, "test", []RuleConfig{
+		{Name: "per-ip", Key: RuleKeyIP, Limit: 2, Window: time.Minute},
+		{Name: "global", Key: RuleKeyGlobal, Limit: 3, Window: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rule set: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+
+	decision, rule, _ := rules.Decide(ruleContext("203.0.113.1"), info)
+	if !decision.Allowed || rule != "per-ip" || decision.Remaining != 1 {
+		t.Errorf("Expected most restrictive decision from per-ip, got %q %+v", rule, decision)
+	}
+	rules.Decide(ruleContext("203.0.113.1"), info)
+	if decision, rule, _ = rules.Decide(ruleContext("203.0.113.1"), info); decision.Allowed || rule != "per-ip" {
+		t.Errorf("Expected per-ip denial, got %q %+v", rule, decision)
+	}
+	if decision, _, _ = rules.Decide(ruleContext("203.0.113.2"), info); !decision.Allowed {
+		t.Error("Expected another IP to be allowed")
+	}
+	if decision, rule, _ = rules.Decide(ruleContext("203.0.113.3"), info); decision.Allowed || rule != "global" {
+		t.Errorf("Expected global denial, got %q %+v", rule, decision)
+	}
+}
+
+// TestRuleSetUpdate 测试热更新时保留未变化规则的状态，配置错误时保留原规则
+func TestRuleSetUpdate(t *testing.T) {
+	configs := []RuleConfig{{Name: "per-ip", Limit: 1, Window: time.Minute}}
+	rules, err := NewRuleSet(nil, "test", configs)
+	if err != nil {
+		t.Fatalf("Failed to create rule set: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+	ctx := ruleContext("203.0.113.1")
+	rules.Decide(ctx, info)
+
+	configs = append(configs, RuleConfig{Name: "global", Key: RuleKeyGlobal, Limit: 10, Window: time.Minute})
+	if err := rules.Update(configs); err != nil {
+		t.Fatalf("Failed to update rules: %v", err)
+	}
+	if decision, rule, _ := rules.Decide(ctx, info); decision.Allowed || rule != "per-ip" {
+		t.Errorf("Expected per-ip state to survive reload, got %q %+v", rule, decision)
+	}
+
+	invalid := []RuleConfig{{Name: "broken", Key: "unknown", Limit: 1, Window: time.Minute}}
+	if err := rules.Update(invalid); err == nil {
+		t.Error("Expected error for invalid key")
+	}
+	if err := rules.Update([]RuleConfig{{Name: "a", Limit: 1, Window: time.Minute}, {Name: "a", Limit: 1, Window: time.Minute}}); err == nil {
+		t.Error("Expected error for duplicate rule names")
+	}
+	if decision, rule, _ := rules.Decide(ctx, info); decision.Allowed || rule != "per-ip" {
+		t.Errorf("Expected previous rules to be kept after failed update, got %q %+v", rule, decision)
+	}
+
+	configs[0].Limit = 5
+	if err := rules.Update(configs); err != nil {
+		t.Fatalf("Failed to update rules: %v", err)
+	}
+	if decision, _, _ := rules.Decide(ctx, info); !decision.Allowed {
+		t.Error("Expected changed limit to start a new limiter")
+	}
+}
+
+// TestLoadRules 测试从YAML读取规则
+func TestLoadRules(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewBufferString(`
+ratelimit:
+  rules:
+    - name: search
+      methods: ["/pkg.Search/*"]
+      metadata:
+        x-plan-tier: [free, trial]
+      key: user
+      algorithm: gcra
+      limit: 10
+      window: 1m
+      burst: 5
+`))
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	configs, err := LoadRules(v, "ratelimit.rules")
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	if len(configs) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(configs))
+	}
+	c := configs[0]
+	if c.Name != "search" || c.Key != RuleKeyUser || c.Algorithm != AlgorithmGCRA || c.Limit != 10 ||
+		c.Window != time.Minute || c.Burst != 5 || len(c.Metadata["x-plan-tier"]) != 2 {
+		t.Errorf("Unexpected rule: %+v", c)
+	}
+}
+
+// TestRuleSetReload 测试手动重新加载规则，失败时保留原有规则
+func TestRuleSetReload(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	read := func(content string) {
+		if err := v.ReadConfig(bytes.NewBufferString(content)); err != nil {
+			t.Fatalf("Failed to read config: %v", err)
+		}
+	}
+	read("ratelimit:\n  rules:\n    - name: global\n      key: global\n      limit: 1\n      window: 1m\n")
+	configs, _ := LoadRules(v, "ratelimit.rules")
+	rules, err := NewRuleSet(nil, "test", configs)
+	if err != nil {
+		t.Fatalf("Failed to create rule set: %v", err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+
+	read("ratelimit:\n  rules:\n    - name: global\n      key: global\n      limit: 100\n      window: 1m\n")
+	if err := rules.Reload(v, "ratelimit.rules"); err != nil {
+		t.Fatalf("Failed to reload rules: %v", err)
+	}
+	if decision, _, _ := rules.Decide(context.Background(), info); decision.Limit != 100 {
+		t.Errorf("Expected reloaded limit 100, got %d", decision.Limit)
+	}
+
+	read("ratelimit:\n  rules:\n    - name: global\n      key: global\n      limit: 0\n      window: 1m\n")
+	if err := rules.Reload(v, "ratelimit.rules"); err == nil {
+		t.Error("Expected invalid rules to fail")
+	}
+	if decision, _, _ := rules.Decide(context.Background(), info); decision.Limit != 100 {
+		t.Errorf("Expected previous rules to be kept, got limit %d", decision.Limit)
+	}
+}
+
+// TestRuleSetWatch 测试配置文件变化后自动更新规则
+func TestRuleSetWatch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(limit string) {
+		content := "ratelimit:\n  rules:\n    - name: global\n      key: global\n      limit: " + limit + "\n      window: 1m\n"
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+	write("1")
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	configs, _ := LoadRules(v, "ratelimit.rules")
+	rules, err := NewRuleSet(nil, "test", configs)
+	if err != nil {
+		t.Fatalf("Failed to create rule set: %v", err)
+	}
+	errs := make(chan error, 1)
+	rules.Watch(v, "ratelimit.rules", func(err error) { errs <- err })
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Method"}
+	rules.Decide(context.Background(), info)
+	write("100")
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		decision, _, _ := rules.Decide(context.Background(), info)
+		if decision.Limit == 100 {
+			return
+		}
+		select {
+		case err := <-errs:
+			t.Fatalf("Failed to reload rules: %v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Error("Expected rules to be reloaded after config change")
+}
+
+// TestInterceptorRules 测试拦截器使用规则集
+func TestInterceptorRules(t *testing.T) {
+	rules, _ := NewRuleSet(nil, "test", []RuleConfig{{Name: "search", Methods: []string{"/pkg.Search/*"}, Limit: 1, Window: time.Minute}})
+	interceptor := UnaryServerInterceptor(&InterceptorConfig{Rules: rules})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	for i := 0; i < 3; i++ {
+		if _, err := interceptor(ruleContext("203.0.113.1"), nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Other/Get"}, handler); err != nil {
+			t.Fatalf("Expected unmatched method to pass, got %v", err)
+		}
+	}
+	interceptor(ruleContext("203.0.113.1"), nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Search/Query"}, handler)
+	_, err := interceptor(ruleContext("203.0.113.1"), nil, &grpc.UnaryServerInfo{FullMethod: "/pkg.Search/Query"}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Expected matched method to be limited, got %v", err)
+	}
+}
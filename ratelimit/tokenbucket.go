@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vera-byte/vgo-kit/cache"
+)
+
+// tokenBucketScript 令牌桶，状态保存在哈希中: tokens 当前令牌数, ts 上次更新时间(毫秒)
+var tokenBucketScript = cache.RegisterScript("ratelimit:token_bucket", `
+	local key = KEYS[1]
+	local rate = tonumber(ARGV[1])
+	local capacity = tonumber(ARGV[2])
+	local now = tonumber(ARGV[3])
+	local requested = tonumber(ARGV[4])
+
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(state[1])
+	local ts = tonumber(state[2])
+	if tokens == nil or ts == nil then
+		tokens = capacity
+		ts = now
+	end
+
+	-- 按经过的时间补充令牌
+	tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+	local allowed = 0
+	local retry_after = 0
+	if tokens >= requested then
+		tokens = tokens - requested
+		allowed = 1
+	elseif requested > capacity then
+		retry_after = -1
+	else
+		retry_after = math.ceil((requested - tokens) / rate)
+	end
+
+	local reset_after = math.ceil((capacity - tokens) / rate)
+	if reset_after > 0 then
+		redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', tostring(now))
+		redis.call('PEXPIRE', key, reset_after)
+	else
+		redis.call('DEL', key)
+	end
+
+	return {allowed, math.floor(tokens), retry_after, reset_after}
+`)
+
+// tokenBucketParams 令牌桶参数，limit个令牌在window内补满，容量为burst
+func tokenBucketParams(limit int, window time.Duration, burst int) (rate float64, capacity int) {
+	if burst <= 0 {
+		burst = limit
+	}
+	return float64(limit) / durationMillis(window), burst
+}
+
+// RedisTokenBucketLimiter Redis实现的令牌桶限流器
+type RedisTokenBucketLimiter struct {
+	client   redis.UniversalClient
+	rate     float64 // 每毫秒补充的令牌数
+	capacity int     // 桶容量
+	prefix   string  // key前缀
+}
+
+// NewRedisTokenBucketLimiter 创建Redis令牌桶限流器，每个window补充limit个令牌，burst为桶容量(<=0时等于limit)
+func NewRedisTokenBucketLimiter(client redis.UniversalClient, limit int, window time.Duration, burst int, prefix string) *RedisTokenBucketLimiter {
+	rate, capacity := tokenBucketParams(limit, window, burst)
+	return &RedisTokenBucketLimiter{
+		client:   client,
+		rate:     rate,
+		capacity: capacity,
+		prefix:   prefix,
+	}
+}
+
+// Decide 尝试获取n个令牌并返回限流决策
+func (r *RedisTokenBucketLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	raw, err := tokenBucketScript.Run(ctx, r.client, []string{r.getKey(key)},
+		r.rate, r.capacity, time.Now().UnixMilli(), n).Result()
+	if err != nil {
+		return nil, err
+	}
+	return parseDecision(raw, r.capacity)
+}
+
+// Allow 检查是否允许请求
+func (r *RedisTokenBucketLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return r.AllowN(ctx, key, 1)
+}
+
+// AllowN 检查是否允许N个请求
+func (r *RedisTokenBucketLimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	result, err := r.Decide(ctx, key, n)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Reset 重置指定key的限制
+func (r *RedisTokenBucketLimiter) Reset(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.getKey(key)).Err()
+}
+
+// GetRemaining 获取剩余令牌数
+func (r *RedisTokenBucketLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
+	result, err := r.Decide(ctx, key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return result.Remaining, nil
+}
+
+// getKey 获取完整的key
+func (r *RedisTokenBucketLimiter) getKey(key string) string {
+	return fmt.Sprintf("%s:%s", r.prefix, key)
+}
+
+// tokenBucket 内存令牌桶状态
+type tokenBucket struct {
+	tokens float64
+	ts     float64 // 上次更新时间(毫秒)
+}
+
+// MemoryTokenBucketLimiter 内存实现的令牌桶限流器，并发安全
+type MemoryTokenBucketLimiter struct {
+	rate     float64
+	capacity int
+	now      func() time.Time
+	buckets  *memoryStore[tokenBucket]
+}
+
+// NewMemoryTokenBucketLimiter 创建内存令牌桶限流器，参数含义与NewRedisTokenBucketLimiter相同
+func NewMemoryTokenBucketLimiter(limit int, window time.Duration, burst int, opts ...MemoryOption) *MemoryTokenBucketLimiter {
+	rate, capacity := tokenBucketParams(limit, window, burst)
+	return &MemoryTokenBucketLimiter{
+		rate:     rate,
+		capacity: capacity,
+		now:      time.Now,
+		buckets:  newMemoryStore[tokenBucket](opts),
+	}
+}
+
+// Decide 尝试获取n个令牌并返回限流决策
+func (m *MemoryTokenBucketLimiter) Decide(ctx context.Context, key string, n int) (*Decision, error) {
+	now := m.now()
+	nowMs := float64(now.UnixMilli())
+	capacity := float64(m.capacity)
+	result := &Decision{Limit: m.capacity}
+
+	m.buckets.update(key, now, func(bucket tokenBucket, ok bool) (tokenBucket, time.Time) {
+		if !ok {
+			bucket = tokenBucket{tokens: capacity, ts: nowMs}
+		}
+
+		// 按经过的时间补充令牌
+		bucket.tokens = math.Min(capacity, bucket.tokens+math.Max(0, nowMs-bucket.ts)*m.rate)
+		bucket.ts = nowMs
+
+		switch requested := float64(n); {
+		case bucket.tokens >= requested:
+			bucket.tokens -= requested
+			result.Allowed = true
+		case requested > capacity:
+			result.RetryAfter = -1
+		default:
+			result.RetryAfter = millisDuration((requested - bucket.tokens) / m.rate)
+		}
+		result.Remaining = int(math.Floor(bucket.tokens))
+		result.ResetAfter = millisDuration((capacity - bucket.tokens) / m.rate)
+
+		// 桶补满后不再保存状态
+		return bucket, now.Add(result.ResetAfter)
+	})
+	return result, nil
+}
+
+// Allow 检查是否允许请求
+func (m *MemoryTokenBucketLimiter) Allow(ctx context.Context, key string) (bool, error) {
+	return m.AllowN(ctx, key, 1)
+}
+
+// AllowN 检查是否允许N个请求
+func (m *MemoryTokenBucketLimiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	result, err := m.Decide(ctx, key, n)
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
+
+// Reset 重置指定key的限制
+func (m *MemoryTokenBucketLimiter) Reset(ctx context.Context, key string) error {
+	m.buckets.delete(key)
+	return nil
+}
+
+// GetRemaining 获取剩余令牌数
+func (m *MemoryTokenBucketLimiter) GetRemaining(ctx context.Context, key string) (int, error) {
+	result, err := m.Decide(ctx, key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return result.Remaining, nil
+}
@@ -57,13 +57,28 @@ type Client interface {
 
 // SentryClient Sentry客户端实现
 type SentryClient struct {
-	hub *sentry.Hub
+	hub     *sentry.Hub
+	ctx     context.Context
+	rules   []FingerprintRule
+	limiter *fingerprintLimiter
+}
+
+// ClientOption Sentry客户端选项
+type ClientOption func(*SentryClient)
+
+// WithFingerprintRules 追加自定义指纹规则
+// 参数: rules ...FingerprintRule 指纹规则
+// 返回: ClientOption 客户端选项
+func WithFingerprintRules(rules ...FingerprintRule) ClientOption {
+	return func(c *SentryClient) {
+		c.rules = append(c.rules, rules...)
+	}
 }
 
 // NewClient 创建新的Sentry客户端
-// 参数: config *SentryConfig Sentry配置
+// 参数: config *SentryConfig Sentry配置, opts ...ClientOption 客户端选项
 // 返回: Client Sentry客户端, error 错误信息
-func NewClient(config *SentryConfig) (Client, error) {
+func NewClient(config *SentryConfig, opts ...ClientOption) (Client, error) {
 	if config == nil {
 		config = DefaultSentryConfig()
 	}
@@ -87,16 +102,45 @@ func NewClient(config *SentryConfig) (Client, error) {
 		return nil, fmt.Errorf("failed to initialize sentry: %w", err)
 	}
 
-	return &SentryClient{
-		hub: sentry.CurrentHub().Clone(),
-	}, nil
+	rules, err := config.GetFingerprintRules()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &SentryClient{
+		hub:   sentry.CurrentHub().Clone(),
+		rules: rules,
+	}
+	if config.Fingerprint.RateWindow > 0 {
+		client.limiter = newFingerprintLimiter(config.Fingerprint.RateWindow, config.Fingerprint.RateLimit)
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client, nil
 }
 
 // CaptureException 捕获异常
+// 按指纹规则分组，相同指纹在限流窗口内超出次数时丢弃
 // 参数: err error 错误对象
-// 返回: *sentry.EventID 事件ID
+// 返回: *sentry.EventID 事件ID，被限流时返回nil
 func (c *SentryClient) CaptureException(err error) *sentry.EventID {
-	return c.hub.CaptureException(err)
+	fingerprint := computeFingerprint(c.ctx, err, c.rules)
+	if len(fingerprint) == 0 {
+		return c.hub.CaptureException(err)
+	}
+
+	if c.limiter != nil && !c.limiter.allow(fingerprint) {
+		return nil
+	}
+
+	var eventID *sentry.EventID
+	c.hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetFingerprint(fingerprint)
+		eventID = c.hub.CaptureException(err)
+	})
+	return eventID
 }
 
 // CaptureMessage 捕获消息
@@ -113,10 +157,9 @@ func (c *SentryClient) CaptureMessage(message string, level sentry.Level) *sentr
 // 参数: ctx context.Context 上下文
 // 返回: Client 带上下文的客户端
 func (c *SentryClient) WithContext(ctx context.Context) Client {
-	newHub := c.hub.Clone()
-	return &SentryClient{
-		hub: newHub,
-	}
+	client := c.clone(c.hub.Clone())
+	client.ctx = ctx
+	return client
 }
 
 // WithTag 添加标签
@@ -127,7 +170,7 @@ func (c *SentryClient) WithTag(key, value string) Client {
 	newHub.ConfigureScope(func(scope *sentry.Scope) {
 		scope.SetTag(key, value)
 	})
-	return &SentryClient{hub: newHub}
+	return c.clone(newHub)
 }
 
 // WithTags 添加多个标签
@@ -140,7 +183,7 @@ func (c *SentryClient) WithTags(tags map[string]string) Client {
 			scope.SetTag(key, value)
 		}
 	})
-	return &SentryClient{hub: newHub}
+	return c.clone(newHub)
 }
 
 // WithExtra 添加额外信息
@@ -151,7 +194,7 @@ func (c *SentryClient) WithExtra(key string, value interface{}) Client {
 	newHub.ConfigureScope(func(scope *sentry.Scope) {
 		scope.SetExtra(key, value)
 	})
-	return &SentryClient{hub: newHub}
+	return c.clone(newHub)
 }
 
 // WithUser 设置用户信息
@@ -162,7 +205,7 @@ func (c *SentryClient) WithUser(user sentry.User) Client {
 	newHub.ConfigureScope(func(scope *sentry.Scope) {
 		scope.SetUser(user)
 	})
-	return &SentryClient{hub: newHub}
+	return c.clone(newHub)
 }
 
 // Flush 刷新缓冲区
@@ -177,6 +220,18 @@ func (c *SentryClient) Close() {
 	c.hub.Flush(2 * time.Second)
 }
 
+// clone 基于新的Hub复制客户端，保留上下文与指纹配置
+// 参数: hub *sentry.Hub 新的Hub
+// 返回: *SentryClient 客户端副本
+func (c *SentryClient) clone(hub *sentry.Hub) *SentryClient {
+	return &SentryClient{
+		hub:     hub,
+		ctx:     c.ctx,
+		rules:   c.rules,
+		limiter: c.limiter,
+	}
+}
+
 // NoOpClient 空操作客户端，用于禁用Sentry时
 type NoOpClient struct{}
 
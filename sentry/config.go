@@ -1,13 +1,25 @@
 package sentry
 
+import "time"
+
 // SentryConfig Sentry 配置结构体
 // 定义Sentry错误监控服务的配置参数
 type SentryConfig struct {
-	Enabled     bool   `yaml:"enabled" json:"enabled"`         // 是否启用 Sentry
-	DSN         string `yaml:"dsn" json:"dsn"`                 // Sentry DSN
-	Environment string `yaml:"environment" json:"environment"` // 环境名称
-	Debug       bool   `yaml:"debug" json:"debug"`             // 是否开启调试模式
+	Enabled     bool    `yaml:"enabled" json:"enabled"`         // 是否启用 Sentry
+	DSN         string  `yaml:"dsn" json:"dsn"`                 // Sentry DSN
+	Environment string  `yaml:"environment" json:"environment"` // 环境名称
+	Debug       bool    `yaml:"debug" json:"debug"`             // 是否开启调试模式
 	SampleRate  float64 `yaml:"sample_rate" json:"sample_rate"` // 采样率 (0.0-1.0)
+	// 错误指纹分组配置
+	Fingerprint FingerprintConfig `yaml:"fingerprint" json:"fingerprint" mapstructure:"fingerprint"`
+}
+
+// FingerprintConfig 错误指纹分组配置
+// 定义异常的分组规则以及相同指纹的上报频率限制
+type FingerprintConfig struct {
+	Rules      []string      `yaml:"rules" json:"rules" mapstructure:"rules"`                   // 指纹规则: grpc_code, method, error_type
+	RateWindow time.Duration `yaml:"rate_window" json:"rate_window" mapstructure:"rate_window"` // 限流时间窗口，为0时不限流
+	RateLimit  int           `yaml:"rate_limit" json:"rate_limit" mapstructure:"rate_limit"`    // 窗口内相同指纹最大上报次数
 }
 
 // GetEnabled 获取是否启用Sentry
@@ -42,3 +54,17 @@ func (c *SentryConfig) GetSampleRate() float64 {
 	}
 	return c.SampleRate
 }
+
+// GetFingerprintRules 获取配置的指纹规则
+// 返回: []FingerprintRule 指纹规则列表, error 错误信息
+func (c *SentryConfig) GetFingerprintRules() ([]FingerprintRule, error) {
+	rules := make([]FingerprintRule, 0, len(c.Fingerprint.Rules))
+	for _, name := range c.Fingerprint.Rules {
+		rule, err := FingerprintRuleByName(name)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
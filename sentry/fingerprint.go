@@ -0,0 +1,177 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// 内置指纹规则名称，用于配置文件
+const (
+	// FingerprintRuleGRPCCode 按gRPC状态码分组
+	FingerprintRuleGRPCCode = "grpc_code"
+	// FingerprintRuleMethod 按gRPC完整方法名分组
+	FingerprintRuleMethod = "method"
+	// FingerprintRuleErrorType 按错误类型分组
+	FingerprintRuleErrorType = "error_type"
+)
+
+// Fingerprinter 自定义指纹接口
+// 领域错误实现该接口后，将直接使用其返回值作为Sentry分组指纹，优先于所有规则
+type Fingerprinter interface {
+	// Fingerprint 返回错误的分组指纹
+	Fingerprint() []string
+}
+
+// FingerprintRule 指纹规则
+// 参数: ctx context.Context 客户端上下文, err error 错误对象
+// 返回: []string 指纹片段，返回nil表示该规则不适用
+type FingerprintRule func(ctx context.Context, err error) []string
+
+// GRPCCodeRule 按gRPC状态码生成指纹片段
+// 返回: FingerprintRule 指纹规则
+func GRPCCodeRule() FingerprintRule {
+	return func(ctx context.Context, err error) []string {
+		st, ok := status.FromError(err)
+		if !ok {
+			return nil
+		}
+		return []string{"grpc_code", st.Code().String()}
+	}
+}
+
+// MethodRule 按gRPC完整方法名生成指纹片段
+// 方法名从 WithContext 设置的服务端上下文中获取
+// 返回: FingerprintRule 指纹规则
+func MethodRule() FingerprintRule {
+	return func(ctx context.Context, err error) []string {
+		if ctx == nil {
+			return nil
+		}
+		method, ok := grpc.Method(ctx)
+		if !ok || method == "" {
+			return nil
+		}
+		return []string{"method", method}
+	}
+}
+
+// ErrorTypeRule 按最内层错误的类型生成指纹片段
+// 返回: FingerprintRule 指纹规则
+func ErrorTypeRule() FingerprintRule {
+	return func(ctx context.Context, err error) []string {
+		root := err
+		for {
+			next := errors.Unwrap(root)
+			if next == nil {
+				break
+			}
+			root = next
+		}
+		return []string{"error_type", fmt.Sprintf("%T", root)}
+	}
+}
+
+// FingerprintRuleByName 根据名称获取内置指纹规则
+// 参数: name string 规则名称
+// 返回: FingerprintRule 指纹规则, error 错误信息
+func FingerprintRuleByName(name string) (FingerprintRule, error) {
+	switch name {
+	case FingerprintRuleGRPCCode:
+		return GRPCCodeRule(), nil
+	case FingerprintRuleMethod:
+		return MethodRule(), nil
+	case FingerprintRuleErrorType:
+		return ErrorTypeRule(), nil
+	default:
+		return nil, fmt.Errorf("unsupported fingerprint rule: %s", name)
+	}
+}
+
+// computeFingerprint 计算错误的分组指纹
+// 参数: ctx context.Context 上下文, err error 错误对象, rules []FingerprintRule 指纹规则
+// 返回: []string 指纹，nil表示使用Sentry默认分组
+func computeFingerprint(ctx context.Context, err error, rules []FingerprintRule) []string {
+	var fp Fingerprinter
+	if errors.As(err, &fp) {
+		if fingerprint := fp.Fingerprint(); len(fingerprint) > 0 {
+			return fingerprint
+		}
+	}
+
+	var fingerprint []string
+	for _, rule := range rules {
+		fingerprint = append(fingerprint, rule(ctx, err)...)
+	}
+	return fingerprint
+}
+
+// fingerprintLimiter 相同指纹的上报限流器
+// 在时间窗口内每个指纹最多上报 limit 次
+type fingerprintLimiter struct {
+	mu        sync.Mutex
+	window    time.Duration
+	limit     int
+	entries   map[string]*fingerprintWindow
+	lastSweep time.Time
+}
+
+// fingerprintWindow 单个指纹的计数窗口
+type fingerprintWindow struct {
+	start time.Time
+	count int
+}
+
+// newFingerprintLimiter 创建指纹限流器
+// 参数: window time.Duration 时间窗口, limit int 窗口内最大上报次数
+// 返回: *fingerprintLimiter 限流器实例
+func newFingerprintLimiter(window time.Duration, limit int) *fingerprintLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &fingerprintLimiter{
+		window:    window,
+		limit:     limit,
+		entries:   make(map[string]*fingerprintWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow 检查指纹是否允许上报
+// 参数: fingerprint []string 指纹
+// 返回: bool 是否允许
+func (l *fingerprintLimiter) allow(fingerprint []string) bool {
+	key := strings.Join(fingerprint, "\x00")
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 定期清理过期窗口，避免内存无限增长
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, entry := range l.entries {
+			if now.Sub(entry.start) >= l.window {
+				delete(l.entries, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, exists := l.entries[key]
+	if !exists || now.Sub(entry.start) >= l.window {
+		l.entries[key] = &fingerprintWindow{start: now, count: 1}
+		return true
+	}
+
+	if entry.count >= l.limit {
+		return false
+	}
+	entry.count++
+	return true
+}
@@ -0,0 +1,83 @@
+package sentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// domainError 实现Fingerprinter的测试错误
+type domainError struct {
+	code string
+}
+
+func (e *domainError) Error() string {
+	return "domain error: " + e.code
+}
+
+func (e *domainError) Fingerprint() []string {
+	return []string{"domain", e.code}
+}
+
+// TestComputeFingerprint 测试指纹计算
+// 验证规则组合以及Fingerprinter优先级
+func TestComputeFingerprint(t *testing.T) {
+	rules := []FingerprintRule{GRPCCodeRule(), ErrorTypeRule()}
+
+	err := status.Error(codes.Unavailable, "connection refused")
+	got := computeFingerprint(context.Background(), err, rules)
+	want := []string{"grpc_code", "Unavailable", "error_type", "*status.Error"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected fingerprint %v, got %v", want, got)
+	}
+
+	wrapped := fmt.Errorf("load user: %w", &domainError{code: "USER_NOT_FOUND"})
+	got = computeFingerprint(context.Background(), wrapped, rules)
+	want = []string{"domain", "USER_NOT_FOUND"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected fingerprint %v, got %v", want, got)
+	}
+
+	if got := computeFingerprint(context.Background(), errors.New("plain"), nil); got != nil {
+		t.Errorf("Expected nil fingerprint without rules, got %v", got)
+	}
+}
+
+// TestFingerprintRuleByName 测试按名称获取规则
+func TestFingerprintRuleByName(t *testing.T) {
+	for _, name := range []string{FingerprintRuleGRPCCode, FingerprintRuleMethod, FingerprintRuleErrorType} {
+		if _, err := FingerprintRuleByName(name); err != nil {
+			t.Errorf("Expected rule %s to be supported, got %v", name, err)
+		}
+	}
+	if _, err := FingerprintRuleByName("unknown"); err == nil {
+		t.Error("Expected error for unknown rule, got nil")
+	}
+}
+
+// TestFingerprintLimiter 测试相同指纹的上报限流
+func TestFingerprintLimiter(t *testing.T) {
+	limiter := newFingerprintLimiter(50*time.Millisecond, 2)
+	fp := []string{"grpc_code", "Unavailable"}
+
+	if !limiter.allow(fp) || !limiter.allow(fp) {
+		t.Fatal("Expected first two events to be allowed")
+	}
+	if limiter.allow(fp) {
+		t.Error("Expected third event within window to be dropped")
+	}
+	if !limiter.allow([]string{"grpc_code", "Internal"}) {
+		t.Error("Expected different fingerprint to be allowed")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !limiter.allow(fp) {
+		t.Error("Expected event to be allowed after window elapsed")
+	}
+}
@@ -20,6 +20,8 @@ var (
 	Metrics     metrics.MetricsCollector
 	Cache       cache.Cache
 	RateLimiter ratelimit.RateLimiter
+	// RateLimitConfig 配置文件中的 ratelimit 节点，通过 RateLimitConfig.InterceptorConfig(RateLimiter) 创建拦截器配置
+	RateLimitConfig *ratelimit.RateLimitConfig
 )
 
 // init 初始化vgokit包的全局变量
@@ -68,6 +70,30 @@ func init() {
 		panic(err)
 	}
 	SentryClient = sentryClient
+
+	// 初始化缓存，默认启动时不检查Redis连接，Redis暂时不可用不影响服务启动，恢复后自动重连
+	cacheConfig := &cache.Config{}
+	cacheConfig.Redis.LazyConnect = true
+	if unmarshalErr := v.UnmarshalKey("cache", cacheConfig); unmarshalErr != nil {
+		panic(unmarshalErr)
+	}
+	cacheInstance, err := cache.New(cacheConfig)
+	if err != nil {
+		panic(err)
+	}
+	Cache = cacheInstance
+
+	// 初始化限流器
+	rateLimitConfig := ratelimit.DefaultRateLimitConfig()
+	if unmarshalErr := v.UnmarshalKey("ratelimit", rateLimitConfig); unmarshalErr != nil {
+		panic(unmarshalErr)
+	}
+	rateLimiter, err := ratelimit.NewRateLimiter(rateLimitConfig)
+	if err != nil {
+		panic(err)
+	}
+	RateLimitConfig = rateLimitConfig
+	RateLimiter = rateLimiter
 }
 
 // isTestEnvironment 检查是否在测试环境中